@@ -0,0 +1,141 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxBreakers is how many circuit breakers a client keeps before forgetting
+// those of hosts whose circuit is closed
+const maxBreakers = 1024
+
+// ErrCircuitOpen is returned for calls to a host whose circuit breaker is
+// open after repeated failures
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// circuitTransport sends each call unless the circuit of the host it is sent
+// to is open
+type circuitTransport struct {
+	base     http.RoundTripper
+	failures int
+	openFor  time.Duration
+	log      *slog.Logger
+	metrics  *clientMetrics
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+func (t *circuitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	b := t.breaker(host)
+	if !b.allow() {
+		return nil, fmt.Errorf("%w: %s", ErrCircuitOpen, host)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	switch {
+	case errors.Is(err, context.Canceled):
+		// The caller gave up, which says nothing about the host
+		b.release()
+	case err != nil || resp.StatusCode >= 500:
+		if b.failure() {
+			t.metrics.circuitOpen.Set(1, host)
+			t.log.WarnContext(req.Context(), "circuit breaker opened", "host", host, "open_for", t.openFor)
+		}
+	default:
+		if b.success() {
+			t.metrics.circuitOpen.Set(0, host)
+			t.log.InfoContext(req.Context(), "circuit breaker closed", "host", host)
+		}
+	}
+	return resp, err
+}
+
+// breaker returns the circuit breaker for host. Once there are maxBreakers,
+// those which are closed are dropped, so calls to many different hosts don't
+// grow the map without bound.
+func (t *circuitTransport) breaker(host string) *breaker {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if b, ok := t.breakers[host]; ok {
+		return b
+	}
+	if len(t.breakers) >= maxBreakers {
+		for h, b := range t.breakers {
+			if !b.open() {
+				delete(t.breakers, h)
+			}
+		}
+	}
+	b := &breaker{failures: t.failures, openFor: t.openFor}
+	t.breakers[host] = b
+	return b
+}
+
+// breaker is the circuit breaker of an upstream host. It opens after a number
+// of consecutive failures, then once openFor has passed lets a single call
+// through, which closes it if it succeeds and opens it again if not.
+type breaker struct {
+	failures int
+	openFor  time.Duration
+
+	mu          sync.Mutex
+	consecutive int
+	openedAt    time.Time
+	probing     bool
+}
+
+// open reports whether the circuit is open, or a trial call is in progress
+func (b *breaker) open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !b.openedAt.IsZero()
+}
+
+// allow reports whether a call may be sent
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.openedAt.IsZero() {
+		return true
+	}
+	if b.probing || time.Since(b.openedAt) < b.openFor {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// success records a successful call, reporting whether it closed the circuit
+func (b *breaker) success() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	closed := !b.openedAt.IsZero()
+	b.consecutive, b.openedAt, b.probing = 0, time.Time{}, false
+	return closed
+}
+
+// failure records a failed call, reporting whether it opened the circuit
+func (b *breaker) failure() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.consecutive++
+	wasOpen := !b.openedAt.IsZero()
+	if b.probing || b.consecutive >= b.failures {
+		b.openedAt, b.probing = time.Now(), false
+	}
+	return !wasOpen && !b.openedAt.IsZero()
+}
+
+// release records a call which was abandoned before its outcome was known
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
@@ -0,0 +1,254 @@
+package client
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/z0mbix/go-microservices-monorepo/pkg/discovery"
+	"github.com/z0mbix/go-microservices-monorepo/pkg/logger"
+	"github.com/z0mbix/go-microservices-monorepo/pkg/metrics"
+	"github.com/z0mbix/go-microservices-monorepo/pkg/service"
+	"github.com/z0mbix/go-microservices-monorepo/pkg/tracing"
+)
+
+const (
+	defaultTimeout          = 30 * time.Second
+	defaultRetries          = 2
+	defaultMinBackoff       = 100 * time.Millisecond
+	defaultMaxBackoff       = 2 * time.Second
+	defaultBreakerFailures  = 5
+	defaultBreakerOpenDelay = 30 * time.Second
+)
+
+// Option configures a client
+type Option func(*transport)
+
+// WithTimeout limits how long a call may take, including retries. Zero means
+// no limit.
+func WithTimeout(d time.Duration) Option {
+	return func(t *transport) {
+		t.timeout = d
+	}
+}
+
+// WithRetries sets how many times a failed call to an idempotent method is
+// retried. Zero disables retries.
+func WithRetries(n int) Option {
+	return func(t *transport) {
+		t.retries = n
+	}
+}
+
+// WithBackoff sets the delay before the first retry, which doubles for each
+// retry after it up to maxDelay. The delays are randomised, so clients
+// retrying at the same time spread out.
+func WithBackoff(delay, maxDelay time.Duration) Option {
+	return func(t *transport) {
+		t.minBackoff, t.maxBackoff = delay, maxDelay
+	}
+}
+
+// WithCircuitBreaker sets how many consecutive failures of an upstream host
+// or service instance open its circuit, failing calls to it without sending them, and how long
+// it stays open before a single call is let through to test it. Zero
+// failures disables the circuit breaker.
+func WithCircuitBreaker(failures int, openFor time.Duration) Option {
+	return func(t *transport) {
+		t.breakerFailures, t.breakerOpenFor = failures, openFor
+	}
+}
+
+// WithTransport sets the transport requests are sent with, instead of
+// http.DefaultTransport
+func WithTransport(rt http.RoundTripper) Option {
+	return func(t *transport) {
+		t.base = rt
+	}
+}
+
+// WithRegistry sends calls addressed to a service's name, e.g.
+// http://billing/invoices, to one of its instances in r, with a circuit
+// breaker for each instance rather than one for the service
+func WithRegistry(r *discovery.Registry) Option {
+	return func(t *transport) {
+		t.registry = r
+	}
+}
+
+// New returns a client for calling other services from svc. Calls:
+//
+//   - carry the request ID and tenant of the request they are made in, and
+//     its trace context
+//   - are retried with exponential backoff when an idempotent method fails
+//     with a network error or a 429, 502, 503 or 504 response
+//   - fail with ErrCircuitOpen while the upstream host's circuit is open
+//     or, with WithRegistry, the circuit of the instance they are sent to
+//   - are recorded in svc's metrics and logged through svc's logger
+func New(svc *service.Service, opts ...Option) *http.Client {
+	t := &transport{
+		timeout:         defaultTimeout,
+		retries:         defaultRetries,
+		minBackoff:      defaultMinBackoff,
+		maxBackoff:      defaultMaxBackoff,
+		breakerFailures: defaultBreakerFailures,
+		breakerOpenFor:  defaultBreakerOpenDelay,
+		log:             slog.New(logger.NewContextHandler(svc.Log.Handler())),
+		metrics:         metricsFor(svc.Metrics),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	// The circuit breakers sit below the registry, so they see the instance
+	// a call is sent to rather than the service's name
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if t.breakerFailures > 0 {
+		base = &circuitTransport{
+			base:     base,
+			failures: t.breakerFailures,
+			openFor:  t.breakerOpenFor,
+			log:      t.log,
+			metrics:  t.metrics,
+			breakers: make(map[string]*breaker),
+		}
+	}
+	if t.registry != nil {
+		base = t.registry.Transport(base)
+	}
+	t.base = tracing.NewTransport(svc.Tracer, base)
+
+	return &http.Client{Transport: t, Timeout: t.timeout}
+}
+
+// transport sends calls with retries
+type transport struct {
+	base            http.RoundTripper
+	registry        *discovery.Registry
+	timeout         time.Duration
+	retries         int
+	minBackoff      time.Duration
+	maxBackoff      time.Duration
+	breakerFailures int
+	breakerOpenFor  time.Duration
+	log             *slog.Logger
+	metrics         *clientMetrics
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	start := time.Now()
+
+	// RoundTrippers must not modify the caller's request
+	req = req.Clone(ctx)
+	if id := service.RequestIDFromContext(ctx); id != "" && req.Header.Get(service.RequestIDHeader) == "" {
+		req.Header.Set(service.RequestIDHeader, id)
+	}
+	if tenant := service.TenantFromContext(ctx); tenant != "" && req.Header.Get(service.TenantHeader) == "" {
+		req.Header.Set(service.TenantHeader, tenant)
+	}
+
+	host := req.URL.Host
+	attempts := 0
+	resp, err := func() (*http.Response, error) {
+		for {
+			attempts++
+			resp, err := t.base.RoundTrip(req)
+			if attempts > t.retries || !retryable(req, resp, err) {
+				return resp, err
+			}
+
+			if resp != nil {
+				_, _ = io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+			}
+			if req.GetBody != nil {
+				if req.Body, err = req.GetBody(); err != nil {
+					return nil, err
+				}
+			}
+			t.metrics.retries.Inc(host, req.Method)
+
+			timer := time.NewTimer(backoff(attempts, t.minBackoff, t.maxBackoff))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, ctx.Err()
+			case <-timer.C:
+			}
+		}
+	}()
+
+	t.record(ctx, req, resp, err, attempts, time.Since(start))
+	return resp, err
+}
+
+// record logs a call and records its metrics, once any retries are done
+func (t *transport) record(ctx context.Context, req *http.Request, resp *http.Response, err error, attempts int, duration time.Duration) {
+	host, method := req.URL.Host, req.Method
+	status := "error"
+	level := slog.LevelDebug
+	attrs := []slog.Attr{
+		slog.String("method", method),
+		slog.String("host", host),
+		slog.String("path", req.URL.Path),
+	}
+	if err != nil {
+		level = slog.LevelWarn
+		attrs = append(attrs, slog.Any("error", err))
+	} else {
+		status = strconv.Itoa(resp.StatusCode)
+		if resp.StatusCode >= 500 {
+			level = slog.LevelWarn
+		}
+		attrs = append(attrs, slog.Int("status", resp.StatusCode))
+	}
+	attrs = append(attrs, slog.Duration("duration", duration), slog.Int("attempts", attempts))
+
+	t.metrics.requests.Inc(host, method, status)
+	t.metrics.duration.Observe(duration.Seconds(), host, method, status)
+	t.log.LogAttrs(ctx, level, "upstream request", attrs...)
+}
+
+// clientMetrics are the metrics recorded for every call
+type clientMetrics struct {
+	requests    *metrics.Counter
+	duration    *metrics.Histogram
+	retries     *metrics.Counter
+	circuitOpen *metrics.Gauge
+}
+
+var (
+	registeredMu sync.Mutex
+	registered   = make(map[*metrics.Registry]*clientMetrics)
+)
+
+// metricsFor returns the client metrics of registry, registering them the
+// first time, as every client of a service records them in the same registry
+func metricsFor(registry *metrics.Registry) *clientMetrics {
+	registeredMu.Lock()
+	defer registeredMu.Unlock()
+
+	if m, ok := registered[registry]; ok {
+		return m
+	}
+	m := &clientMetrics{
+		requests: registry.NewCounter("http_client_requests_total",
+			"Total number of calls to upstream services.", "host", "method", "status"),
+		duration: registry.NewHistogram("http_client_request_duration_seconds",
+			"Duration of calls to upstream services in seconds, including retries.", metrics.DefaultBuckets, "host", "method", "status"),
+		retries: registry.NewCounter("http_client_retries_total",
+			"Total number of retried calls to upstream services.", "host", "method"),
+		circuitOpen: registry.NewGauge("http_client_circuit_open",
+			"Whether the circuit breaker of an upstream host is open.", "host"),
+	}
+	registered[registry] = m
+	return m
+}
@@ -0,0 +1,297 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/z0mbix/go-microservices-monorepo/pkg/config"
+	"github.com/z0mbix/go-microservices-monorepo/pkg/discovery"
+	"github.com/z0mbix/go-microservices-monorepo/pkg/metrics"
+	"github.com/z0mbix/go-microservices-monorepo/pkg/service"
+	"github.com/z0mbix/go-microservices-monorepo/pkg/tracing"
+)
+
+func newTestService(t *testing.T) (*service.Service, *bytes.Buffer) {
+	t.Helper()
+	var buf bytes.Buffer
+	svc, err := service.NewWithName("order",
+		service.WithLogger(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))),
+		service.WithLogLevel("debug"),
+		service.WithMetricsRegistry(metrics.NewRegistry()),
+	)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	return svc, &buf
+}
+
+// upstream responds with the given statuses in turn, then 200
+func upstream(t *testing.T, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1))
+		if n <= len(statuses) {
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+		io.Copy(w, r.Body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestRetries(t *testing.T) {
+	svc, _ := newTestService(t)
+	client := New(svc, WithBackoff(time.Millisecond, 5*time.Millisecond))
+
+	tests := []struct {
+		name          string
+		method        string
+		header        string
+		statuses      []int
+		expectedCalls int32
+		expectedCode  int
+	}{
+		{"get retried", http.MethodGet, "", []int{503, 502}, 3, http.StatusOK},
+		{"retries exhausted", http.MethodGet, "", []int{503, 503, 503, 503}, 3, http.StatusServiceUnavailable},
+		{"client error not retried", http.MethodGet, "", []int{404}, 1, http.StatusNotFound},
+		{"internal error not retried", http.MethodPut, "", []int{500}, 1, http.StatusInternalServerError},
+		{"post not retried", http.MethodPost, "", []int{503}, 1, http.StatusServiceUnavailable},
+		{"post with idempotency key retried", http.MethodPost, "key-1", []int{429}, 2, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, calls := upstream(t, tt.statuses...)
+			req, _ := http.NewRequest(tt.method, srv.URL, strings.NewReader("body"))
+			if tt.header != "" {
+				req.Header.Set("Idempotency-Key", tt.header)
+			}
+
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.expectedCode {
+				t.Errorf("expected status %d, got %d", tt.expectedCode, resp.StatusCode)
+			}
+			if calls.Load() != tt.expectedCalls {
+				t.Errorf("expected %d calls, got %d", tt.expectedCalls, calls.Load())
+			}
+			if resp.StatusCode == http.StatusOK && string(body) != "body" {
+				t.Errorf("expected the body to be sent again, got %q", body)
+			}
+		})
+	}
+
+	t.Run("cancelled while waiting", func(t *testing.T) {
+		srv, calls := upstream(t, 503, 503)
+		client := New(svc, WithBackoff(time.Second, time.Second))
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+		start := time.Now()
+		if _, err := client.Do(req); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected the context deadline, got %v", err)
+		}
+		if time.Since(start) > 500*time.Millisecond || calls.Load() != 1 {
+			t.Errorf("expected to stop waiting once the context is done, made %d calls", calls.Load())
+		}
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	for attempts, limit := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 400 * time.Millisecond, 10: time.Second} {
+		for range 100 {
+			d := backoff(attempts, 100*time.Millisecond, time.Second)
+			if d < limit/2 || d > limit {
+				t.Fatalf("expected the delay after %d attempts to be between %v and %v, got %v", attempts, limit/2, limit, d)
+			}
+		}
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	svc, buf := newTestService(t)
+	srv, calls := upstream(t, 500, 500, 500)
+	client := New(svc, WithRetries(0), WithCircuitBreaker(2, 50*time.Millisecond))
+
+	get := func() (int, error) {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+
+	for range 2 {
+		if status, err := get(); err != nil || status != http.StatusInternalServerError {
+			t.Fatalf("expected the upstream's error, got %d %v", status, err)
+		}
+	}
+	if _, err := get(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected the circuit to be open, got %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("expected no call while the circuit is open, got %d calls", calls.Load())
+	}
+	if !strings.Contains(buf.String(), `"msg":"circuit breaker opened"`) {
+		t.Errorf("expected the circuit opening to be logged, got: %s", buf.String())
+	}
+
+	// A failed trial call opens the circuit again
+	time.Sleep(60 * time.Millisecond)
+	if status, _ := get(); status != http.StatusInternalServerError {
+		t.Fatalf("expected a trial call once the circuit has been open long enough, got %d", status)
+	}
+	if _, err := get(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected the circuit to open again, got %v", err)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if status, err := get(); err != nil || status != http.StatusOK {
+		t.Fatalf("expected a successful trial call, got %d %v", status, err)
+	}
+	if status, err := get(); err != nil || status != http.StatusOK {
+		t.Errorf("expected the circuit to be closed, got %d %v", status, err)
+	}
+
+	var out bytes.Buffer
+	svc.Metrics.WriteTo(&out)
+	host := strings.TrimPrefix(srv.URL, "http://")
+	for _, want := range []string{
+		`http_client_circuit_open{host="` + host + `"} 0`,
+		`http_client_requests_total{host="` + host + `",method="GET",status="error"} 2`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected metrics to contain %s, got:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestCircuitBreakerPerInstance(t *testing.T) {
+	svc, _ := newTestService(t)
+	bad, _ := upstream(t, 500, 500, 500, 500)
+	good, goodCalls := upstream(t)
+
+	cfg, err := config.NewWithArgs(nil, config.WithDefaultPort(8002))
+	if err != nil {
+		t.Fatalf("failed to create config: %v", err)
+	}
+	upstreams, err := discovery.Load(cfg, []string{"billing"}, discovery.WithEndpoints("billing", bad.URL, good.URL))
+	if err != nil {
+		t.Fatalf("failed to load the registry: %v", err)
+	}
+	client := New(svc, WithRetries(0), WithCircuitBreaker(1, time.Minute), WithRegistry(upstreams))
+
+	var open int
+	for range 6 {
+		resp, err := client.Get("http://billing/invoices")
+		if errors.Is(err, ErrCircuitOpen) {
+			open++
+			continue
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp.Body.Close()
+	}
+	// The first call to the bad instance opens its circuit, the later ones
+	// fail without being sent and the good instance is unaffected
+	if open != 2 || goodCalls.Load() != 3 {
+		t.Errorf("expected only the failing instance's circuit to open, got %d open and %d calls to the good instance", open, goodCalls.Load())
+	}
+}
+
+func TestCircuitBreakerLimit(t *testing.T) {
+	svc, _ := newTestService(t)
+	srv, _ := upstream(t, 500)
+	rt := &circuitTransport{
+		base:     http.DefaultTransport,
+		failures: 1,
+		openFor:  time.Minute,
+		log:      svc.Log,
+		metrics:  metricsFor(svc.Metrics),
+		breakers: make(map[string]*breaker),
+	}
+	client := &http.Client{Transport: rt}
+
+	// Opens the circuit of the upstream, which is kept once the limit is reached
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	for i := range maxBreakers {
+		rt.breaker(fmt.Sprintf("host-%d", i))
+	}
+
+	if len(rt.breakers) > maxBreakers {
+		t.Errorf("expected at most %d breakers, got %d", maxBreakers, len(rt.breakers))
+	}
+	if _, err := client.Get(srv.URL); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected the open circuit to be kept, got %v", err)
+	}
+}
+
+func TestPropagation(t *testing.T) {
+	svc, buf := newTestService(t)
+	var headers http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header.Clone()
+	}))
+	defer srv.Close()
+
+	client := New(svc)
+	// A second client of the same service shares its metrics
+	New(svc)
+
+	svc.HandleFunc("GET /orders", func(w http.ResponseWriter, r *http.Request) {
+		req, _ := http.NewRequestWithContext(r.Context(), http.MethodGet, srv.URL+"/invoices", nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		resp.Body.Close()
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.Header.Set(service.RequestIDHeader, "req-123")
+	req.Header.Set(service.TenantHeader, "acme")
+	svc.Handler().ServeHTTP(httptest.NewRecorder(), req)
+
+	if headers.Get(service.RequestIDHeader) != "req-123" || headers.Get(service.TenantHeader) != "acme" {
+		t.Errorf("expected the request ID and tenant to be propagated, got %v", headers)
+	}
+	if _, ok := tracing.Extract(headers); !ok {
+		t.Errorf("expected the trace context to be propagated, got %v", headers)
+	}
+
+	for _, want := range []string{`"msg":"upstream request"`, `"path":"/invoices"`, `"status":200`, `"attempts":1`, `"request_id":"req-123"`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected logs to contain %s, got: %s", want, buf.String())
+		}
+	}
+
+	var out bytes.Buffer
+	svc.Metrics.WriteTo(&out)
+	if !strings.Contains(out.String(), `http_client_requests_total{host="`+strings.TrimPrefix(srv.URL, "http://")+`",method="GET",status="200"} 1`) {
+		t.Errorf("expected the call to be counted, got:\n%s", out.String())
+	}
+}
@@ -0,0 +1,56 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// retryable reports whether a call can be retried after the response or
+// error of an attempt. Only idempotent calls are retried, as a failed call
+// may still have had an effect, and only when their body can be sent again.
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	if !idempotent(req) || (req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+		return false
+	}
+	if err != nil {
+		return !errors.Is(err, context.Canceled) &&
+			!errors.Is(err, context.DeadlineExceeded) &&
+			!errors.Is(err, ErrCircuitOpen)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// idempotent reports whether repeating req has the same effect as sending it
+// once, either because of its method or because it has an idempotency key,
+// as for net/http's own retries
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	_, key := req.Header["Idempotency-Key"]
+	_, xKey := req.Header["X-Idempotency-Key"]
+	return key || xKey
+}
+
+// backoff returns the delay before retrying after the given number of
+// attempts: delay doubled for each attempt after the first, up to maxDelay,
+// of which a random half is kept so retries from many clients spread out
+func backoff(attempts int, delay, maxDelay time.Duration) time.Duration {
+	d := delay
+	for i := 1; i < attempts && d < maxDelay; i++ {
+		d *= 2
+	}
+	d = min(d, maxDelay)
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
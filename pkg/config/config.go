@@ -1,48 +1,200 @@
 package config
 
 import (
-	"cmp"
+	"errors"
 	"fmt"
-	"os"
 	"strconv"
+	"time"
+)
+
+// Production defaults for the HTTP server
+const (
+	DefaultReadHeaderTimeout = 5 * time.Second
+	DefaultReadTimeout       = 30 * time.Second
+	DefaultWriteTimeout      = 30 * time.Second
+	DefaultIdleTimeout       = 120 * time.Second
+	DefaultMaxHeaderBytes    = 1 << 20
 )
 
 type Config struct {
 	Port        int
+	AdminPort   int
 	LogLevel    string
+	LogFormat   string
+	LogOutput   string
 	Environment string
+	HTTP        HTTPConfig
+	TLS         TLSConfig
+	// Features are the names of the enabled feature flags
+	Features []string
+	// FeatureFlagsFile defines feature flags with rollouts and targeting,
+	// see the featureflag package
+	FeatureFlagsFile string
+	// File is the config file named by APP_CONFIG_FILE, if any
+	File string
+	// ServiceRegistryFile lists the endpoints of other services, see the
+	// discovery package
+	ServiceRegistryFile string
+
+	loader  *loader
+	sources map[string]Source
+	watch   *watcher
+}
+
+// HTTPConfig holds the HTTP server timeouts and limits
+type HTTPConfig struct {
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	// MaxConnections limits concurrent connections, 0 means no limit
+	MaxConnections int
 }
 
+// TLSConfig holds the certificate files used to serve HTTPS, TLS is disabled
+// when they are empty
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile enables mutual TLS, requiring client certificates signed by it
+	ClientCAFile string
+}
+
+// Option changes the Config once it has been loaded
 type Option func(*Config) error
 
-// WithDefaultPort sets the default port, but allows override via environment variable
+// WithDefaultPort sets the default port, but allows override via environment
+// variable, config file or flag
 func WithDefaultPort(port int) Option {
 	return func(c *Config) error {
 		c.Port = port
-		portFromEnvVariable := os.Getenv("APP_PORT")
-		if portFromEnvVariable != "" {
-			parsedPort, err := strconv.Atoi(portFromEnvVariable)
-			if err != nil {
-				return fmt.Errorf("invalid port in APP_PORT environment variable: %w", err)
-			}
-			c.Port = parsedPort
+		c.setSource(Source{Key: "APP_PORT", Value: strconv.Itoa(port), Layer: LayerDefault})
+
+		value, src, ok := c.lookup("APP_PORT")
+		if !ok {
+			return nil
 		}
+		parsedPort, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid port in %s: %w", src.describe(), err)
+		}
+		c.Port = parsedPort
+		c.setSource(src)
 		return nil
 	}
 }
 
-// New creates a new Config with the provided options
+// New creates a new Config with the provided options, see NewWithArgs
 func New(opts ...Option) (*Config, error) {
-	cfg := &Config{
-		Environment: cmp.Or(os.Getenv("APP_ENV"), "local"),
-		LogLevel:    cmp.Or(os.Getenv("APP_LOG_LEVEL"), "info"),
+	return NewWithArgs(nil, opts...)
+}
+
+// NewWithArgs creates a new Config from, in increasing order of precedence:
+//
+//  1. built-in defaults
+//  2. the YAML, JSON or TOML file named by APP_CONFIG_FILE
+//  3. the override of that file for the environment, e.g. config.production.yaml
+//  4. environment variables
+//  5. command line flags parsed from args, e.g. -log-level for APP_LOG_LEVEL
+//
+// and then applies opts and checks the result with Validate. Config files use
+// the environment variable names in lower case without the APP_ prefix,
+// nesting on underscores where they like, e.g. http: {read_timeout: 10s} sets
+// APP_HTTP_READ_TIMEOUT. The error lists every invalid value.
+func NewWithArgs(args []string, opts ...Option) (*Config, error) {
+	l, err := newLoader(args)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{loader: l, sources: make(map[string]Source)}
+	// Every problem is reported at once, skipping validation of the settings
+	// which could not be parsed
+	var errs []error
+	invalid := make(map[string]bool)
+	for _, s := range settings {
+		if s.manual {
+			continue
+		}
+		value, src, ok := l.lookup(s.key)
+		if !ok {
+			value, src = s.def, Source{Key: s.key, Value: s.def, Layer: LayerDefault}
+		}
+		if err := s.set(cfg, value); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s in %s: %w", s.kind, src.describe(), err))
+			invalid[s.key] = true
+			continue
+		}
+		cfg.sources[s.key] = src
 	}
 
 	for _, opt := range opts {
 		if err := opt(cfg); err != nil {
-			return nil, err
+			errs = append(errs, err)
 		}
 	}
 
+	errs = append(errs, cfg.problems(invalid)...)
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	cfg.watch = &watcher{args: args, opts: opts, interval: defaultWatchInterval}
+	cfg.watch.current.Store(cfg)
 	return cfg, nil
 }
+
+// lookup finds a setting in the config files, environment and flags the
+// Config was loaded from, or only the environment if it was not loaded by New
+func (c *Config) lookup(key string) (string, Source, bool) {
+	if c.loader == nil {
+		c.loader = &loader{}
+	}
+	return c.loader.lookup(key)
+}
+
+func (c *Config) setSource(src Source) {
+	if c.sources == nil {
+		c.sources = make(map[string]Source)
+	}
+	c.sources[src.Key] = src
+}
+
+func (c HTTPConfig) validate(skip map[string]bool) []error {
+	var errs []error
+	durations := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"APP_HTTP_READ_HEADER_TIMEOUT", c.ReadHeaderTimeout},
+		{"APP_HTTP_READ_TIMEOUT", c.ReadTimeout},
+		{"APP_HTTP_WRITE_TIMEOUT", c.WriteTimeout},
+		{"APP_HTTP_IDLE_TIMEOUT", c.IdleTimeout},
+	}
+	for _, d := range durations {
+		if d.value <= 0 && !skip[d.name] {
+			errs = append(errs, fmt.Errorf("invalid timeout in %s: must be greater than zero, got %s", d.name, d.value))
+		}
+	}
+
+	if c.MaxHeaderBytes <= 0 && !skip["APP_HTTP_MAX_HEADER_BYTES"] {
+		errs = append(errs, fmt.Errorf("invalid value in APP_HTTP_MAX_HEADER_BYTES: must be greater than zero, got %d", c.MaxHeaderBytes))
+	}
+	if c.MaxConnections < 0 && !skip["APP_HTTP_MAX_CONNECTIONS"] {
+		errs = append(errs, fmt.Errorf("invalid value in APP_HTTP_MAX_CONNECTIONS: must not be negative, got %d", c.MaxConnections))
+	}
+
+	return errs
+}
+
+func (c TLSConfig) validate() []error {
+	var errs []error
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		errs = append(errs, fmt.Errorf("invalid TLS configuration: APP_TLS_CERT_FILE and APP_TLS_KEY_FILE must be set together"))
+	}
+	if c.ClientCAFile != "" && c.CertFile == "" {
+		errs = append(errs, fmt.Errorf("invalid TLS configuration: APP_TLS_CLIENT_CA_FILE requires APP_TLS_CERT_FILE and APP_TLS_KEY_FILE"))
+	}
+	return errs
+}
@@ -2,8 +2,11 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestNew(t *testing.T) {
@@ -133,7 +136,7 @@ func TestNew(t *testing.T) {
 		// Apply multiple options
 		cfg, err := New(
 			WithDefaultPort(8888),
-			withTestLogLevel("trace"),
+			withTestLogLevel("warn"),
 			withTestEnv("staging"),
 		)
 		if err != nil {
@@ -144,8 +147,8 @@ func TestNew(t *testing.T) {
 		if cfg.Port != 8888 {
 			t.Errorf("expected Port to be 8888, got %d", cfg.Port)
 		}
-		if cfg.LogLevel != "trace" {
-			t.Errorf("expected LogLevel to be 'trace', got %q", cfg.LogLevel)
+		if cfg.LogLevel != "warn" {
+			t.Errorf("expected LogLevel to be 'warn', got %q", cfg.LogLevel)
 		}
 		if cfg.Environment != "staging" {
 			t.Errorf("expected Environment to be 'staging', got %q", cfg.Environment)
@@ -167,11 +170,10 @@ func TestWithDefaultPort(t *testing.T) {
 		setupFunction func()
 	}{
 		{
-			name:         "negative default port",
-			defaultPort:  -1,
-			envPort:      "",
-			expectedPort: -1,
-			expectError:  false,
+			name:        "negative default port",
+			defaultPort: -1,
+			envPort:     "",
+			expectError: true,
 		},
 		{
 			name:         "zero default port",
@@ -181,11 +183,16 @@ func TestWithDefaultPort(t *testing.T) {
 			expectError:  false,
 		},
 		{
-			name:         "very large default port",
-			defaultPort:  99999,
-			envPort:      "",
-			expectedPort: 99999,
-			expectError:  false,
+			name:        "very large default port",
+			defaultPort: 99999,
+			envPort:     "",
+			expectError: true,
+		},
+		{
+			name:        "environment port out of range",
+			defaultPort: 8080,
+			envPort:     "65536",
+			expectError: true,
 		},
 		{
 			name:         "empty environment port uses default",
@@ -243,3 +250,445 @@ func TestWithDefaultPort(t *testing.T) {
 		})
 	}
 }
+
+func TestHTTPConfig(t *testing.T) {
+	envVars := []string{
+		"APP_HTTP_READ_HEADER_TIMEOUT",
+		"APP_HTTP_READ_TIMEOUT",
+		"APP_HTTP_WRITE_TIMEOUT",
+		"APP_HTTP_IDLE_TIMEOUT",
+		"APP_HTTP_MAX_HEADER_BYTES",
+		"APP_HTTP_MAX_CONNECTIONS",
+	}
+
+	// Save original environment to restore after tests
+	original := make(map[string]string)
+	for _, name := range envVars {
+		original[name] = os.Getenv(name)
+	}
+	defer func() {
+		for name, value := range original {
+			os.Setenv(name, value)
+		}
+	}()
+
+	clearEnv := func() {
+		for _, name := range envVars {
+			os.Unsetenv(name)
+		}
+	}
+
+	t.Run("production defaults", func(t *testing.T) {
+		clearEnv()
+
+		cfg, err := New()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		expected := HTTPConfig{
+			ReadHeaderTimeout: DefaultReadHeaderTimeout,
+			ReadTimeout:       DefaultReadTimeout,
+			WriteTimeout:      DefaultWriteTimeout,
+			IdleTimeout:       DefaultIdleTimeout,
+			MaxHeaderBytes:    DefaultMaxHeaderBytes,
+			MaxConnections:    0,
+		}
+		if cfg.HTTP != expected {
+			t.Errorf("expected HTTP config %+v, got %+v", expected, cfg.HTTP)
+		}
+	})
+
+	t.Run("environment variables override defaults", func(t *testing.T) {
+		clearEnv()
+		os.Setenv("APP_HTTP_READ_HEADER_TIMEOUT", "2s")
+		os.Setenv("APP_HTTP_READ_TIMEOUT", "10s")
+		os.Setenv("APP_HTTP_WRITE_TIMEOUT", "1m")
+		os.Setenv("APP_HTTP_IDLE_TIMEOUT", "90s")
+		os.Setenv("APP_HTTP_MAX_HEADER_BYTES", "8192")
+		os.Setenv("APP_HTTP_MAX_CONNECTIONS", "500")
+
+		cfg, err := New()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		expected := HTTPConfig{
+			ReadHeaderTimeout: 2 * time.Second,
+			ReadTimeout:       10 * time.Second,
+			WriteTimeout:      time.Minute,
+			IdleTimeout:       90 * time.Second,
+			MaxHeaderBytes:    8192,
+			MaxConnections:    500,
+		}
+		if cfg.HTTP != expected {
+			t.Errorf("expected HTTP config %+v, got %+v", expected, cfg.HTTP)
+		}
+	})
+
+	invalid := []struct {
+		name  string
+		value string
+	}{
+		{"APP_HTTP_READ_TIMEOUT", "thirty"},
+		{"APP_HTTP_READ_TIMEOUT", "30"},
+		{"APP_HTTP_WRITE_TIMEOUT", "-1s"},
+		{"APP_HTTP_IDLE_TIMEOUT", "0s"},
+		{"APP_HTTP_MAX_HEADER_BYTES", "lots"},
+		{"APP_HTTP_MAX_HEADER_BYTES", "0"},
+		{"APP_HTTP_MAX_CONNECTIONS", "-10"},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name+"="+tt.value+" returns error", func(t *testing.T) {
+			clearEnv()
+			os.Setenv(tt.name, tt.value)
+
+			_, err := New()
+			if err == nil {
+				t.Fatal("expected error but got nil")
+			}
+			if !strings.Contains(err.Error(), tt.name) {
+				t.Errorf("expected error to name %s, got %v", tt.name, err)
+			}
+		})
+	}
+}
+
+func TestTLSConfig(t *testing.T) {
+	envVars := []string{"APP_TLS_CERT_FILE", "APP_TLS_KEY_FILE", "APP_TLS_CLIENT_CA_FILE"}
+
+	original := make(map[string]string)
+	for _, name := range envVars {
+		original[name] = os.Getenv(name)
+	}
+	defer func() {
+		for name, value := range original {
+			os.Setenv(name, value)
+		}
+	}()
+
+	tests := []struct {
+		name        string
+		env         map[string]string
+		expected    TLSConfig
+		expectError bool
+	}{
+		{
+			name:     "disabled by default",
+			env:      map[string]string{},
+			expected: TLSConfig{},
+		},
+		{
+			name: "certificate and key",
+			env: map[string]string{
+				"APP_TLS_CERT_FILE": "/etc/tls/tls.crt",
+				"APP_TLS_KEY_FILE":  "/etc/tls/tls.key",
+			},
+			expected: TLSConfig{CertFile: "/etc/tls/tls.crt", KeyFile: "/etc/tls/tls.key"},
+		},
+		{
+			name: "mutual TLS",
+			env: map[string]string{
+				"APP_TLS_CERT_FILE":      "/etc/tls/tls.crt",
+				"APP_TLS_KEY_FILE":       "/etc/tls/tls.key",
+				"APP_TLS_CLIENT_CA_FILE": "/etc/tls/ca.crt",
+			},
+			expected: TLSConfig{CertFile: "/etc/tls/tls.crt", KeyFile: "/etc/tls/tls.key", ClientCAFile: "/etc/tls/ca.crt"},
+		},
+		{
+			name:        "certificate without key",
+			env:         map[string]string{"APP_TLS_CERT_FILE": "/etc/tls/tls.crt"},
+			expectError: true,
+		},
+		{
+			name:        "client CA without certificate",
+			env:         map[string]string{"APP_TLS_CLIENT_CA_FILE": "/etc/tls/ca.crt"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range envVars {
+				os.Unsetenv(name)
+			}
+			for name, value := range tt.env {
+				os.Setenv(name, value)
+			}
+
+			cfg, err := New()
+			if tt.expectError {
+				if err == nil {
+					t.Error("expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg.TLS != tt.expected {
+				t.Errorf("expected TLS config %+v, got %+v", tt.expected, cfg.TLS)
+			}
+		})
+	}
+}
+
+func TestAdminPort(t *testing.T) {
+	originalAdminPort := os.Getenv("APP_ADMIN_PORT")
+	defer os.Setenv("APP_ADMIN_PORT", originalAdminPort)
+
+	tests := []struct {
+		name         string
+		envPort      string
+		expectedPort int
+		expectError  bool
+	}{
+		{name: "disabled by default", envPort: "", expectedPort: 0},
+		{name: "set from environment", envPort: "9090", expectedPort: 9090},
+		{name: "invalid port string", envPort: "admin", expectError: true},
+		{name: "negative port", envPort: "-1", expectError: true},
+		{name: "port out of range", envPort: "70000", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Setenv("APP_ADMIN_PORT", tt.envPort)
+
+			cfg, err := New()
+			if tt.expectError {
+				if err == nil {
+					t.Error("expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg.AdminPort != tt.expectedPort {
+				t.Errorf("expected AdminPort to be %d, got %d", tt.expectedPort, cfg.AdminPort)
+			}
+		})
+	}
+}
+
+func TestLogFormatAndOutput(t *testing.T) {
+	originalFormat := os.Getenv("APP_LOG_FORMAT")
+	originalOutput := os.Getenv("APP_LOG_OUTPUT")
+	defer func() {
+		os.Setenv("APP_LOG_FORMAT", originalFormat)
+		os.Setenv("APP_LOG_OUTPUT", originalOutput)
+	}()
+
+	os.Setenv("APP_LOG_FORMAT", "")
+	os.Setenv("APP_LOG_OUTPUT", "")
+	cfg, err := New()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.LogFormat != "json" || cfg.LogOutput != "stdout" {
+		t.Errorf("expected json to stdout by default, got %q to %q", cfg.LogFormat, cfg.LogOutput)
+	}
+
+	os.Setenv("APP_LOG_FORMAT", "pretty")
+	os.Setenv("APP_LOG_OUTPUT", "file:///var/log/app.log?max_size=10MB")
+	cfg, err = New()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.LogFormat != "pretty" {
+		t.Errorf("expected LogFormat to be pretty, got %q", cfg.LogFormat)
+	}
+	if cfg.LogOutput != "file:///var/log/app.log?max_size=10MB" {
+		t.Errorf("expected LogOutput from environment, got %q", cfg.LogOutput)
+	}
+}
+
+func TestLayers(t *testing.T) {
+	envVars := []string{"APP_CONFIG_FILE", "APP_ENV", "APP_PORT", "APP_LOG_LEVEL", "APP_LOG_FORMAT", "APP_HTTP_READ_TIMEOUT", "APP_HTTP_IDLE_TIMEOUT"}
+	original := make(map[string]string)
+	for _, name := range envVars {
+		original[name] = os.Getenv(name)
+	}
+	defer func() {
+		for name, value := range original {
+			os.Setenv(name, value)
+		}
+	}()
+	clearEnv := func() {
+		for _, name := range envVars {
+			os.Unsetenv(name)
+		}
+	}
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yaml")
+	writeConfig := func(path, data string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+	writeConfig(file, "port: 9000\nlog_level: warn\nlog_format: text\nhttp:\n  read_timeout: 10s\n  idle_timeout: 3m\n")
+	writeConfig(filepath.Join(dir, "config.production.yaml"), "log_level: error\nhttp:\n  read_timeout: 20s\n")
+
+	t.Run("file overrides defaults", func(t *testing.T) {
+		clearEnv()
+		os.Setenv("APP_CONFIG_FILE", file)
+
+		cfg, err := New(WithDefaultPort(8001))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.Port != 9000 || cfg.LogLevel != "warn" || cfg.HTTP.ReadTimeout != 10*time.Second {
+			t.Errorf("expected values from the config file, got %+v", cfg)
+		}
+		if cfg.File != file {
+			t.Errorf("expected File to be %q, got %q", file, cfg.File)
+		}
+	})
+
+	t.Run("environment file overrides file", func(t *testing.T) {
+		clearEnv()
+		os.Setenv("APP_CONFIG_FILE", file)
+		os.Setenv("APP_ENV", "production")
+
+		cfg, err := New()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.LogLevel != "error" || cfg.HTTP.ReadTimeout != 20*time.Second {
+			t.Errorf("expected values from the environment file, got %+v", cfg)
+		}
+		if cfg.LogFormat != "text" || cfg.HTTP.IdleTimeout != 3*time.Minute {
+			t.Errorf("expected values missing from the environment file to come from the file, got %+v", cfg)
+		}
+	})
+
+	t.Run("env overrides files and flags override env", func(t *testing.T) {
+		clearEnv()
+		os.Setenv("APP_CONFIG_FILE", file)
+		os.Setenv("APP_ENV", "production")
+		os.Setenv("APP_LOG_LEVEL", "info")
+		os.Setenv("APP_PORT", "9100")
+
+		cfg, err := NewWithArgs([]string{"-log-level", "debug", "-http-read-timeout=1s"}, WithDefaultPort(8001))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.Port != 9100 {
+			t.Errorf("expected Port from the environment, got %d", cfg.Port)
+		}
+		if cfg.LogLevel != "debug" || cfg.HTTP.ReadTimeout != time.Second {
+			t.Errorf("expected values from flags, got %+v", cfg)
+		}
+	})
+
+	t.Run("flags choose the config file and environment", func(t *testing.T) {
+		clearEnv()
+
+		cfg, err := NewWithArgs([]string{"-config-file", file, "-env", "production"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.Environment != "production" || cfg.LogLevel != "error" {
+			t.Errorf("expected values from the environment file, got %+v", cfg)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		clearEnv()
+		invalidFile := filepath.Join(dir, "invalid.toml")
+		writeConfig(invalidFile, "[http]\nread_timeout = \"soon\"\n")
+
+		tests := []struct {
+			name     string
+			file     string
+			args     []string
+			contains string
+		}{
+			{name: "missing file", file: filepath.Join(dir, "missing.yaml"), contains: "missing.yaml"},
+			{name: "invalid value in file", file: invalidFile, contains: "APP_HTTP_READ_TIMEOUT (config file " + invalidFile + ")"},
+			{name: "invalid flag value", args: []string{"-http-max-connections", "many"}, contains: "APP_HTTP_MAX_CONNECTIONS (flag -http-max-connections)"},
+			{name: "unknown flag", args: []string{"-colour"}, contains: "colour"},
+			{name: "unexpected argument", args: []string{"serve"}, contains: "serve"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				os.Setenv("APP_CONFIG_FILE", tt.file)
+				_, err := NewWithArgs(tt.args)
+				if err == nil {
+					t.Fatal("expected error but got nil")
+				}
+				if !strings.Contains(err.Error(), tt.contains) {
+					t.Errorf("expected error to contain %q, got %v", tt.contains, err)
+				}
+			})
+		}
+	})
+}
+
+func TestSources(t *testing.T) {
+	envVars := []string{"APP_CONFIG_FILE", "APP_ENV", "APP_PORT", "APP_LOG_LEVEL"}
+	original := make(map[string]string)
+	for _, name := range envVars {
+		original[name] = os.Getenv(name)
+		os.Unsetenv(name)
+	}
+	defer func() {
+		for name, value := range original {
+			os.Setenv(name, value)
+		}
+	}()
+
+	file := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(file, []byte(`{"log_format": "pretty", "service": {"billing": {"url": "http://billing:8001"}}}`), 0o644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	os.Setenv("APP_CONFIG_FILE", file)
+	os.Setenv("APP_ENV", "staging")
+	t.Setenv("APP_SERVICE_USER_URL", "http://user:8004")
+
+	withAdminPort := func(c *Config) error {
+		c.AdminPort = 9090
+		return nil
+	}
+	cfg, err := NewWithArgs([]string{"-log-level=debug"}, WithDefaultPort(8001), withAdminPort)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sources := make(map[string]Source)
+	for _, src := range cfg.Sources() {
+		sources[src.Key] = src
+	}
+
+	expected := map[string]Source{
+		"APP_PORT":              {Key: "APP_PORT", Value: "8001", Layer: LayerDefault},
+		"APP_ADMIN_PORT":        {Key: "APP_ADMIN_PORT", Value: "9090", Layer: LayerOption},
+		"APP_ENV":               {Key: "APP_ENV", Value: "staging", Layer: LayerEnv},
+		"APP_LOG_LEVEL":         {Key: "APP_LOG_LEVEL", Value: "debug", Layer: LayerFlag},
+		"APP_LOG_FORMAT":        {Key: "APP_LOG_FORMAT", Value: "pretty", Layer: LayerFile, File: file},
+		"APP_HTTP_IDLE_TIMEOUT": {Key: "APP_HTTP_IDLE_TIMEOUT", Value: "2m0s", Layer: LayerDefault},
+	}
+	for key, want := range expected {
+		if got := sources[key]; got != want {
+			t.Errorf("expected source %v, got %v", want, got)
+		}
+	}
+	if got := sources["APP_LOG_FORMAT"].String(); got != "APP_LOG_FORMAT=pretty (file "+file+")" {
+		t.Errorf("unexpected source string %q", got)
+	}
+
+	lookups := map[string]Source{
+		"APP_SERVICE_BILLING_URL": {Key: "APP_SERVICE_BILLING_URL", Value: "http://billing:8001", Layer: LayerFile, File: file},
+		"APP_SERVICE_USER_URL":    {Key: "APP_SERVICE_USER_URL", Value: "http://user:8004", Layer: LayerEnv},
+	}
+	for key, want := range lookups {
+		if got, ok := cfg.Lookup(key); !ok || got != want {
+			t.Errorf("expected lookup of %s to return %v, got %v", key, want, got)
+		}
+	}
+	if _, ok := cfg.Lookup("APP_SERVICE_SHIPPING_URL"); ok {
+		t.Error("expected lookup of an unset variable to fail")
+	}
+}
@@ -0,0 +1,522 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReadFile parses a YAML, JSON or TOML config file, chosen by extension,
+// into flattened keys: nested keys are joined with underscores and upper
+// cased, so http.read_timeout becomes HTTP_READ_TIMEOUT, matching the
+// environment variable APP_HTTP_READ_TIMEOUT. Lists become comma separated.
+func ReadFile(path string) (map[string]string, error) {
+	return readFile(path, flatKey)
+}
+
+// ReadFileSections parses a config file like ReadFile, but keeps each top
+// level key, as written, apart from the keys nested under it, so names
+// containing underscores can't be confused with nesting. The values of
+// sections[name] are keyed by their flattened key below name, or "" for a
+// value of name itself.
+func ReadFileSections(path string) (map[string]map[string]string, error) {
+	values, err := readFile(path, sectionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	sections := make(map[string]map[string]string)
+	for key, value := range values {
+		name, nested, _ := strings.Cut(key, sectionSeparator)
+		if sections[name] == nil {
+			sections[name] = make(map[string]string)
+		}
+		sections[name][nested] = value
+	}
+	return sections, nil
+}
+
+// keyFunc turns the path of keys leading to a value into the key it is
+// stored under
+type keyFunc func(path []string) string
+
+func readFile(path string, keyOf keyFunc) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	var values map[string]string
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		values, err = parseYAML(data, keyOf)
+	case ".json":
+		values, err = parseJSON(data, keyOf)
+	case ".toml":
+		values, err = parseTOML(data, keyOf)
+	default:
+		return nil, fmt.Errorf("unsupported config file format %q: expected .yaml, .yml, .json or .toml", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	return values, nil
+}
+
+// environmentFile returns the per-environment override of a config file,
+// e.g. config.production.yaml for config.yaml
+func environmentFile(path, environment string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + environment + ext
+}
+
+// flatKey joins a key path into the upper case, underscore separated form
+func flatKey(path []string) string {
+	key := strings.Join(path, "_")
+	return strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+}
+
+// sectionSeparator separates the top level key from the flattened nested
+// key in the keys made by sectionKey
+const sectionSeparator = "\x00"
+
+func sectionKey(path []string) string {
+	return path[0] + sectionSeparator + flatKey(path[1:])
+}
+
+func parseJSON(data []byte, keyOf keyFunc) (map[string]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var doc map[string]any
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string)
+	if err := flattenJSON(values, keyOf, nil, doc); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func flattenJSON(values map[string]string, keyOf keyFunc, path []string, v any) error {
+	switch v := v.(type) {
+	case map[string]any:
+		for name, child := range v {
+			if err := flattenJSON(values, keyOf, append(path, name), child); err != nil {
+				return err
+			}
+		}
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			s, ok := jsonScalar(item)
+			if !ok {
+				return fmt.Errorf("%s: lists may only contain strings, numbers and booleans", strings.Join(path, "."))
+			}
+			items[i] = s
+		}
+		values[keyOf(path)] = strings.Join(items, ",")
+	default:
+		s, _ := jsonScalar(v)
+		values[keyOf(path)] = s
+	}
+	return nil
+}
+
+func jsonScalar(v any) (string, bool) {
+	switch v := v.(type) {
+	case nil:
+		return "", true
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	default:
+		return "", false
+	}
+}
+
+// parseYAML parses the subset of YAML used for configuration: nested
+// mappings, scalars, quoted strings, comments and lists, either as
+// "- item" lines or [a, b]. Anchors, block scalars and lists of mappings
+// are not supported. Inconsistent indentation and duplicate keys are errors.
+func parseYAML(data []byte, keyOf keyFunc) (map[string]string, error) {
+	// level is a key whose value is a nested mapping or list. Its entries
+	// must all be indented alike, by child spaces, and be either keys or
+	// list items.
+	type level struct {
+		indent int
+		path   []string
+		child  int
+		items  bool
+	}
+
+	values := make(map[string]string)
+	var (
+		stack   []level
+		root    = level{indent: -1, child: -1}
+		list    []string            // key path of the mapping entry "- item" lines belong to
+		parents = map[string]bool{} // keys with nested keys
+	)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := stripComment(scanner.Text())
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		if strings.HasPrefix(line, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", n)
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		item, isItem := strings.CutPrefix(trimmed, "-")
+		isItem = isItem && (item == "" || item[0] == ' ')
+
+		// List items may be indented at the same level as their key
+		for len(stack) > 0 {
+			top := stack[len(stack)-1]
+			if indent > top.indent || (isItem && indent == top.indent && slices.Equal(top.path, list)) {
+				break
+			}
+			stack = stack[:len(stack)-1]
+		}
+		open := &root
+		if len(stack) > 0 {
+			open = &stack[len(stack)-1]
+		}
+		parent := open.path
+
+		switch {
+		case open.child == -1:
+			open.child, open.items = indent, isItem
+		case indent != open.child:
+			return nil, fmt.Errorf("line %d: indentation does not match an open level", n)
+		case isItem != open.items:
+			return nil, fmt.Errorf("line %d: list items and keys can't be mixed", n)
+		}
+
+		if isItem {
+			if list == nil || !slices.Equal(list, parent) {
+				return nil, fmt.Errorf("line %d: list item without a key", n)
+			}
+			item = strings.TrimSpace(item)
+			if quoted := strings.HasPrefix(item, `"`) || strings.HasPrefix(item, "'"); !quoted &&
+				(strings.HasSuffix(item, ":") || strings.Contains(item, ": ")) {
+				return nil, fmt.Errorf("line %d: lists of mappings are not supported", n)
+			}
+			value, err := yamlScalar(item)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			key := keyOf(list)
+			if existing := values[key]; existing != "" {
+				value = existing + "," + value
+			}
+			values[key] = value
+			continue
+		}
+
+		rawKey, rawValue, found := strings.Cut(trimmed, ":")
+		if !found || (rawValue != "" && rawValue[0] != ' ') {
+			return nil, fmt.Errorf("line %d: expected \"key: value\"", n)
+		}
+		key, err := yamlScalar(strings.TrimSpace(rawKey))
+		if err != nil || key == "" {
+			return nil, fmt.Errorf("line %d: invalid key %q", n, rawKey)
+		}
+
+		path := append(append([]string(nil), parent...), key)
+		if _, ok := values[keyOf(path)]; ok {
+			return nil, fmt.Errorf("line %d: duplicate key %q", n, key)
+		}
+		rawValue = strings.TrimSpace(rawValue)
+		if rawValue == "" {
+			// A nested mapping or list follows
+			stack = append(stack, level{indent: indent, path: path, child: -1})
+			list = path
+			values[keyOf(path)] = ""
+		} else {
+			value, err := yamlValue(rawValue)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			values[keyOf(path)] = value
+		}
+		if parent != nil {
+			parents[keyOf(parent)] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// Keys which only introduced a nested mapping have no value of their own
+	for key := range parents {
+		if values[key] == "" {
+			delete(values, key)
+		}
+	}
+	return values, nil
+}
+
+func yamlValue(s string) (string, error) {
+	switch {
+	case strings.HasPrefix(s, "["):
+		return flowList(s, yamlScalar)
+	case strings.HasPrefix(s, "{"):
+		return "", fmt.Errorf("inline mappings are not supported")
+	case strings.HasPrefix(s, "|"), strings.HasPrefix(s, ">"):
+		return "", fmt.Errorf("block scalars are not supported")
+	case strings.HasPrefix(s, "&"), strings.HasPrefix(s, "*"):
+		return "", fmt.Errorf("anchors and aliases are not supported")
+	}
+	return yamlScalar(s)
+}
+
+func yamlScalar(s string) (string, error) {
+	switch {
+	case len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"':
+		return strconv.Unquote(s)
+	case len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'':
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	case s == "~" || s == "null":
+		return "", nil
+	case strings.HasPrefix(s, `"`), strings.HasPrefix(s, "'"):
+		return "", fmt.Errorf("unterminated string %s", s)
+	}
+	return s, nil
+}
+
+// parseTOML parses the subset of TOML used for configuration: tables,
+// dotted keys, strings, numbers, booleans, dates and single line arrays.
+// Multi-line strings, inline tables and arrays of tables are not supported.
+// Unquoted strings and duplicate keys are errors.
+func parseTOML(data []byte, keyOf keyFunc) (map[string]string, error) {
+	values := make(map[string]string)
+	var table []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(stripComment(scanner.Text()))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[[") {
+			return nil, fmt.Errorf("line %d: arrays of tables are not supported", n)
+		}
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: invalid table header", n)
+			}
+			keys, err := tomlKey(line[1 : len(line)-1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			table = keys
+			continue
+		}
+
+		rawKey, rawValue, found := strings.Cut(line, "=")
+		if !found {
+			return nil, fmt.Errorf("line %d: expected \"key = value\"", n)
+		}
+		keys, err := tomlKey(rawKey)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		value, err := tomlValue(strings.TrimSpace(rawValue))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		key := keyOf(append(append([]string(nil), table...), keys...))
+		if _, ok := values[key]; ok {
+			return nil, fmt.Errorf("line %d: duplicate key %q", n, strings.TrimSpace(rawKey))
+		}
+		values[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func tomlKey(s string) ([]string, error) {
+	var keys []string
+	for part := range strings.SplitSeq(s, ".") {
+		part = strings.TrimSpace(part)
+		key, err := tomlString(part)
+		if err != nil || key == "" || (key == part && !bareKey(key)) {
+			return nil, fmt.Errorf("invalid key %q", strings.TrimSpace(s))
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func tomlValue(s string) (string, error) {
+	switch {
+	case s == "":
+		return "", fmt.Errorf("missing value")
+	case strings.HasPrefix(s, `"""`), strings.HasPrefix(s, "'''"):
+		return "", fmt.Errorf("multi-line strings are not supported")
+	case strings.HasPrefix(s, "["):
+		return flowList(s, tomlScalar)
+	case strings.HasPrefix(s, "{"):
+		return "", fmt.Errorf("inline tables are not supported")
+	}
+	return tomlScalar(s)
+}
+
+// tomlDateLayouts are the forms of TOML's dates and times, which are
+// returned as they are
+var tomlDateLayouts = []string{
+	time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05Z07:00", "2006-01-02 15:04:05", time.DateOnly, time.TimeOnly,
+}
+
+// tomlScalar unquotes strings and removes the underscores TOML allows in
+// numbers, e.g. 1_000, returning booleans and dates as they are. Anything
+// else is an unquoted string, which TOML doesn't allow.
+func tomlScalar(s string) (string, error) {
+	if s == "" || s[0] == '"' || s[0] == '\'' {
+		return tomlString(s)
+	}
+	if s == "true" || s == "false" {
+		return s, nil
+	}
+	for _, layout := range tomlDateLayouts {
+		if _, err := time.Parse(layout, s); err == nil {
+			return s, nil
+		}
+	}
+	number := strings.ReplaceAll(s, "_", "")
+	if _, err := strconv.ParseFloat(number, 64); err == nil {
+		return number, nil
+	}
+	if _, err := strconv.ParseInt(number, 0, 64); err == nil {
+		return number, nil
+	}
+	return "", fmt.Errorf("invalid value %s: strings must be quoted", s)
+}
+
+// bareKey reports whether s may be used unquoted as a TOML key
+func bareKey(s string) bool {
+	for _, c := range s {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// tomlString unquotes basic and literal strings, returning bare keys and
+// values as they are
+func tomlString(s string) (string, error) {
+	switch {
+	case len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"':
+		return strconv.Unquote(s)
+	case len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'':
+		return s[1 : len(s)-1], nil
+	case strings.HasPrefix(s, `"`), strings.HasPrefix(s, "'"):
+		return "", fmt.Errorf("unterminated string %s", s)
+	}
+	return s, nil
+}
+
+// flowList parses a single line [a, "b", c] list into a comma separated
+// string, unquoting each item with unquote
+func flowList(s string, unquote func(string) (string, error)) (string, error) {
+	if !strings.HasSuffix(s, "]") {
+		return "", fmt.Errorf("unterminated list %q", s)
+	}
+	inner := strings.TrimSpace(s[1 : len(s)-1])
+	if inner == "" {
+		return "", nil
+	}
+
+	var items []string
+	for _, raw := range splitOutsideQuotes(inner, ',') {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue // trailing comma
+		}
+		if strings.HasPrefix(raw, "[") || strings.HasPrefix(raw, "{") {
+			return "", fmt.Errorf("nested lists and mappings are not supported")
+		}
+		item, err := unquote(raw)
+		if err != nil {
+			return "", fmt.Errorf("invalid list item %s: %w", raw, err)
+		}
+		items = append(items, item)
+	}
+	return strings.Join(items, ","), nil
+}
+
+// stripComment removes a # comment, which starts the line or follows
+// whitespace and is not inside a quoted string
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && tokenStart(line, i):
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// splitOutsideQuotes splits s at sep, ignoring separators in quoted strings
+func splitOutsideQuotes(s string, sep byte) []string {
+	var (
+		parts []string
+		quote byte
+		start int
+	)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && tokenStart(s, i):
+			quote = c
+		case c == sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// tokenStart reports whether s[i] starts a value, so quotes in the middle of
+// unquoted text such as "it's" are not treated as strings
+func tokenStart(s string, i int) bool {
+	if i == 0 {
+		return true
+	}
+	return strings.IndexByte(" \t[,:=", s[i-1]) >= 0
+}
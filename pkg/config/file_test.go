@@ -0,0 +1,217 @@
+package config
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var expectedFileValues = map[string]string{
+	"PORT":              "9000",
+	"LOG_LEVEL":         "debug",
+	"HTTP_READ_TIMEOUT": "10s",
+	"HTTP_IDLE_TIMEOUT": "1m",
+	"TLS_CERT_FILE":     "/etc/certs/tls #1.crt",
+	"CARRIERS":          "ups,fedex,dhl",
+	"EMPTY":             "",
+}
+
+func TestParseYAML(t *testing.T) {
+	data := `
+# service settings
+port: 9000
+log_level: 'debug'   # trailing comment
+http:
+  read_timeout: 10s
+  idle_timeout: "1m"
+tls:
+  cert_file: "/etc/certs/tls #1.crt"
+carriers:
+  - ups
+  - fedex
+  - "dhl"
+empty: ~
+`
+	values, err := parseYAML([]byte(data), flatKey)
+	if err != nil {
+		t.Fatalf("parseYAML returned unexpected error: %v", err)
+	}
+	if !maps.Equal(values, expectedFileValues) {
+		t.Errorf("expected %v, got %v", expectedFileValues, values)
+	}
+
+	t.Run("lists at the key's indentation and inline", func(t *testing.T) {
+		values, err := parseYAML([]byte("carriers:\n- ups\n- \"fedex: ground\"\n- http://dhl\nregions: [eu, 'us-east']\nnote: it's # fine\n"), flatKey)
+		if err != nil {
+			t.Fatalf("parseYAML returned unexpected error: %v", err)
+		}
+		expected := map[string]string{"CARRIERS": "ups,fedex: ground,http://dhl", "REGIONS": "eu,us-east", "NOTE": "it's"}
+		if !maps.Equal(values, expected) {
+			t.Errorf("expected %v, got %v", expected, values)
+		}
+	})
+
+	invalid := map[string]string{
+		"missing colon":  "port 9000\n",
+		"orphan item":    "- ups\n",
+		"block scalar":   "text: |\n  hello\n",
+		"inline mapping": "http: {port: 1}\n",
+		"tab indent":     "http:\n\tport: 1\n",
+		"anchor":         "port: &port 1\n",
+		"dedent":         "a:\n    b: 1\n  c: 2\n",
+		"indent":         "a:\n  b: 1\n    c: 2\n",
+		"nested scalar":  "a: 1\n  b: 2\n",
+		"items and keys": "a:\n  - 1\n  b: 2\n",
+		"duplicate key":  "a: 1\na: 2\n",
+		"mapping item":   "carriers:\n  - name: ups\n",
+		"nested item":    "carriers:\n  - ups:\n      rate: 1\n",
+	}
+	for name, data := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseYAML([]byte(data), flatKey); err == nil {
+				t.Errorf("expected error parsing %q", data)
+			}
+		})
+	}
+
+	_, err = parseYAML([]byte("carriers:\n  - ups\n  - name: fedex\n"), flatKey)
+	if err == nil || err.Error() != "line 3: lists of mappings are not supported" {
+		t.Errorf("expected the mapping item's line to be reported, got %v", err)
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	data := `{
+		"port": 9000,
+		"log_level": "debug",
+		"http": {"read_timeout": "10s", "idle_timeout": "1m"},
+		"tls": {"cert_file": "/etc/certs/tls #1.crt"},
+		"carriers": ["ups", "fedex", "dhl"],
+		"empty": null
+	}`
+	values, err := parseJSON([]byte(data), flatKey)
+	if err != nil {
+		t.Fatalf("parseJSON returned unexpected error: %v", err)
+	}
+	if !maps.Equal(values, expectedFileValues) {
+		t.Errorf("expected %v, got %v", expectedFileValues, values)
+	}
+
+	if _, err := parseJSON([]byte(`{"carriers": [{"name": "ups"}]}`), flatKey); err == nil {
+		t.Error("expected error for a list of objects")
+	}
+	if _, err := parseJSON([]byte(`{"port": }`), flatKey); err == nil {
+		t.Error("expected error for invalid JSON")
+	}
+}
+
+func TestParseTOML(t *testing.T) {
+	data := `
+# service settings
+port = 9_000
+log_level = 'debug' # trailing comment
+carriers = ["ups", "fedex", 'dhl']
+empty = ""
+
+[http]
+read_timeout = "10s"
+idle_timeout = "1m"
+
+[tls]
+cert_file = "/etc/certs/tls #1.crt"
+`
+	values, err := parseTOML([]byte(data), flatKey)
+	if err != nil {
+		t.Fatalf("parseTOML returned unexpected error: %v", err)
+	}
+	if !maps.Equal(values, expectedFileValues) {
+		t.Errorf("expected %v, got %v", expectedFileValues, values)
+	}
+
+	values, err = parseTOML([]byte("http.max_connections = 10\n\"tls\".key_file = \"key.pem\"\n"), flatKey)
+	if err != nil {
+		t.Fatalf("parseTOML returned unexpected error: %v", err)
+	}
+	if values["HTTP_MAX_CONNECTIONS"] != "10" || values["TLS_KEY_FILE"] != "key.pem" {
+		t.Errorf("expected dotted keys to be flattened, got %v", values)
+	}
+
+	values, err = parseTOML([]byte("released = 1979-05-27T07:32:00Z\nstarts = 07:32:00\nratio = -0.5\nmask = 0xff\ndebug = true\n"), flatKey)
+	if err != nil {
+		t.Fatalf("parseTOML returned unexpected error: %v", err)
+	}
+	expected := map[string]string{"RELEASED": "1979-05-27T07:32:00Z", "STARTS": "07:32:00", "RATIO": "-0.5", "MASK": "0xff", "DEBUG": "true"}
+	if !maps.Equal(values, expected) {
+		t.Errorf("expected %v, got %v", expected, values)
+	}
+
+	invalid := map[string]string{
+		"missing equals":   "port 9000\n",
+		"array of tables":  "[[carriers]]\n",
+		"inline table":     "http = {port = 1}\n",
+		"multi-line":       "text = \"\"\"\nhello\n\"\"\"\n",
+		"unclosed header":  "[http\n",
+		"unclosed array":   "carriers = [\"ups\"\n",
+		"missing value":    "port =\n",
+		"invalid key":      "a..b = 1\n",
+		"bad string quote": "name = \"unterminated\n",
+		"bare string":      "log_level = debug\n",
+		"bare list item":   "carriers = [ups]\n",
+		"bare key space":   "log level = \"debug\"\n",
+		"table key space":  "[http server]\n",
+		"duplicate key":    "port = 1\nport = 2\n",
+		"duplicate table":  "[http]\nport = 1\n[http]\nport = 2\n",
+	}
+	for name, data := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseTOML([]byte(data), flatKey); err == nil {
+				t.Errorf("expected error parsing %q", data)
+			}
+		})
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "config.ini")
+	os.WriteFile(path, []byte("port=1\n"), 0o644)
+	if _, err := ReadFile(path); err == nil {
+		t.Error("expected error for an unsupported format")
+	}
+
+	if _, err := ReadFile(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("expected error for a missing file")
+	}
+
+	if got := environmentFile("/etc/app/config.yaml", "production"); got != "/etc/app/config.production.yaml" {
+		t.Errorf("unexpected environment file %q", got)
+	}
+}
+
+func TestReadFileSections(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"sections.yaml": "premium_users: true\npremium:\n  users: [a, b]\n  read-timeout: 1s\n",
+		"sections.json": `{"premium_users": true, "premium": {"users": ["a", "b"], "read-timeout": "1s"}}`,
+		"sections.toml": "premium_users = true\n[premium]\nusers = [\"a\", \"b\"]\nread-timeout = \"1s\"\n",
+	}
+	expected := map[string]map[string]string{
+		"premium_users": {"": "true"},
+		"premium":       {"USERS": "a,b", "READ_TIMEOUT": "1s"},
+	}
+	for name, data := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			os.WriteFile(path, []byte(data), 0o644)
+			sections, err := ReadFileSections(path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !maps.EqualFunc(sections, expected, maps.Equal) {
+				t.Errorf("expected %v, got %v", expected, sections)
+			}
+		})
+	}
+}
@@ -0,0 +1,355 @@
+package config
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrRequired is reported for a required value which is not set
+var ErrRequired = errors.New("required value is not set")
+
+// FieldError describes a value Load could not set
+type FieldError struct {
+	// Key is the environment variable for the field, or the field's path,
+	// e.g. Shipping.Carriers, when it has none. Errors from a struct's
+	// Validate method have the struct's path, or its type name at the top.
+	Key string
+	Err error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Key, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// LoadOption configures Load
+type LoadOption func(*loadOptions)
+
+type loadOptions struct {
+	prefix    string
+	file      string
+	secretKey []byte
+}
+
+// WithPrefix adds prefix to the name of every environment variable, e.g.
+// WithPrefix("BILLING_") loads `env:"CURRENCY"` from BILLING_CURRENCY
+func WithPrefix(prefix string) LoadOption {
+	return func(o *loadOptions) {
+		o.prefix = prefix
+	}
+}
+
+// FromFile reads values from the config file at path rather than the file
+// named by APP_CONFIG_FILE
+func FromFile(path string) LoadOption {
+	return func(o *loadOptions) {
+		o.file = path
+	}
+}
+
+// WithSecretKey sets the AES key used to decrypt enc: secrets, rather than
+// reading it from the file named by APP_SECRET_KEY_FILE
+func WithSecretKey(key []byte) LoadOption {
+	return func(o *loadOptions) {
+		o.secretKey = key
+	}
+}
+
+var (
+	secretType   = reflect.TypeFor[Secret]()
+	durationType = reflect.TypeFor[time.Duration]()
+	urlType      = reflect.TypeFor[url.URL]()
+	textType     = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// Load returns a T populated from the config file and environment, which T
+// describes with struct tags:
+//
+//	type Settings struct {
+//		Currency string        `env:"BILLING_CURRENCY" default:"USD" required:"true"`
+//		Carriers []string      `env:"SHIPPING_CARRIERS" default:"ups,fedex"`
+//		Timeout  time.Duration `env:"BILLING_TIMEOUT" default:"5s"`
+//	}
+//
+// Strings, bools, numbers, durations, URLs, Secrets and
+// encoding.TextUnmarshaler types are supported, along with pointers to them. Slices are comma separated
+// lists and maps are comma separated key=value pairs. The env tag of a nested
+// struct is a prefix for the names of its fields.
+//
+// Values which are set are checked against the comma separated rules in a
+// validate tag, e.g. `validate:"min=1,max=65535"`:
+//
+//	min=N      numbers and durations must be at least N, strings, slices and
+//	           maps must have at least N elements
+//	max=N      the opposite of min
+//	oneof=a b  the value must be one of the space separated values
+//	url        the value must be an absolute URL
+//
+// Once every field of a struct is valid, Validate is called if the struct
+// implements Validator.
+//
+// Values are looked up like those of New, so config files may set
+// BILLING_CURRENCY as billing.currency. Empty values are treated as unset.
+// Every missing, malformed or invalid value is reported in the returned
+// error, which joins a FieldError for each.
+func Load[T any](opts ...LoadOption) (*T, error) {
+	var o loadOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	l := &loader{}
+	path := o.file
+	if path == "" {
+		path, _, _ = l.lookup("APP_CONFIG_FILE")
+	}
+	if path != "" {
+		if err := l.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	v := new(T)
+	rv := reflect.ValueOf(v).Elem()
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot load configuration into %s: not a struct", rv.Type())
+	}
+
+	sl := &structLoader{loader: l, secretKey: o.secretKey}
+	sl.load(rv, o.prefix, "")
+	if len(sl.errs) > 0 {
+		return nil, errors.Join(sl.errs...)
+	}
+	return v, nil
+}
+
+// structLoader sets the fields of a struct passed to Load, collecting a
+// FieldError for each which could not be set
+type structLoader struct {
+	*loader
+	secretKey []byte
+	errs      []error
+}
+
+// key returns the key for enc: secrets, reading it from APP_SECRET_KEY_FILE
+// the first time it is needed
+func (sl *structLoader) key() ([]byte, error) {
+	if sl.secretKey != nil {
+		return sl.secretKey, nil
+	}
+	path, _, ok := sl.lookup("APP_SECRET_KEY_FILE")
+	if !ok {
+		return nil, errors.New("decrypting secrets requires a key in APP_SECRET_KEY_FILE")
+	}
+	key, err := readSecretKey(path)
+	if err != nil {
+		return nil, err
+	}
+	sl.secretKey = key
+	return key, nil
+}
+
+// load sets the tagged fields of v, a struct
+func (sl *structLoader) load(v reflect.Value, prefix, path string) {
+	loaded := len(sl.errs)
+	for i := range v.NumField() {
+		f := v.Type().Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		field := v.Field(i)
+		name := f.Name
+		if path != "" {
+			name = path + "." + f.Name
+		}
+		env := f.Tag.Get("env")
+
+		if isNested(f.Type) {
+			if f.Type.Kind() == reflect.Pointer {
+				if field.IsNil() {
+					field.Set(reflect.New(f.Type.Elem()))
+				}
+				field = field.Elem()
+			}
+			sl.load(field, prefix+env, name)
+			continue
+		}
+
+		key := name
+		var value string
+		if env != "" {
+			key = prefix + env
+			value, _, _ = sl.lookup(key)
+		}
+		if value == "" {
+			value = f.Tag.Get("default")
+		}
+
+		if value == "" {
+			required, err := strconv.ParseBool(f.Tag.Get("required"))
+			if err != nil && f.Tag.Get("required") != "" {
+				sl.errs = append(sl.errs, &FieldError{Key: key, Err: fmt.Errorf("invalid required tag: %w", err)})
+			} else if required {
+				sl.errs = append(sl.errs, &FieldError{Key: key, Err: ErrRequired})
+			}
+			continue
+		}
+
+		if field.Type() == secretType {
+			secret, err := resolveSecret(value, sl.key)
+			if err != nil {
+				sl.errs = append(sl.errs, &FieldError{Key: key, Err: fmt.Errorf("invalid secret: %w", err)})
+				continue
+			}
+			field.Set(reflect.ValueOf(secret))
+			continue
+		}
+
+		if err := setValue(field, value); err != nil {
+			sl.errs = append(sl.errs, &FieldError{Key: key, Err: fmt.Errorf("invalid value %q: %w", value, err)})
+			continue
+		}
+		for _, err := range checkRules(field, f.Tag.Get("validate")) {
+			sl.errs = append(sl.errs, &FieldError{Key: key, Err: err})
+		}
+	}
+
+	// Validate is only called once every field has a valid value
+	if len(sl.errs) > loaded {
+		return
+	}
+	if validator, ok := v.Addr().Interface().(Validator); ok {
+		if err := validator.Validate(); err != nil {
+			var fe *FieldError
+			if !errors.As(err, &fe) {
+				key := path
+				if key == "" {
+					key = v.Type().Name()
+				}
+				err = &FieldError{Key: key, Err: err}
+			}
+			sl.errs = append(sl.errs, err)
+		}
+	}
+}
+
+// isNested reports whether fields of type t are loaded as a group rather than
+// from a single value
+func isNested(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && t != urlType && t != secretType && !reflect.PointerTo(t).Implements(textType)
+}
+
+// setValue parses s into v, which must be addressable
+func setValue(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setValue(v.Elem(), s)
+	}
+
+	switch v.Type() {
+	case durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	case urlType:
+		u, err := url.Parse(s)
+		if err != nil {
+			return err
+		}
+		if !u.IsAbs() {
+			return errors.New("URL must be absolute")
+		}
+		v.Set(reflect.ValueOf(*u))
+		return nil
+	}
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		items := splitList(s)
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setValue(slice.Index(i), item); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	case reflect.Map:
+		m := reflect.MakeMap(v.Type())
+		for _, item := range splitList(s) {
+			k, val, ok := strings.Cut(item, "=")
+			if !ok {
+				return fmt.Errorf("%q is not a key=value pair", item)
+			}
+			key := reflect.New(v.Type().Key()).Elem()
+			if err := setValue(key, strings.TrimSpace(k)); err != nil {
+				return err
+			}
+			value := reflect.New(v.Type().Elem()).Elem()
+			if err := setValue(value, strings.TrimSpace(val)); err != nil {
+				return err
+			}
+			m.SetMapIndex(key, value)
+		}
+		v.Set(m)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// splitList splits a comma separated list, dropping empty items
+func splitList(s string) []string {
+	var items []string
+	for item := range strings.SplitSeq(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"errors"
+	"log/slog"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testBilling struct {
+	Currency string `env:"CURRENCY" default:"USD" required:"true"`
+	Retries  int    `env:"RETRIES" default:"3"`
+}
+
+type testSettings struct {
+	Billing  testBilling `env:"BILLING_"`
+	Carriers []string    `env:"SHIPPING_CARRIERS" default:"ups,fedex"`
+	Weights  map[string]float64
+	Labels   map[string]string `env:"LABELS"`
+	Timeout  time.Duration     `env:"TIMEOUT" default:"5s"`
+	Debug    bool              `env:"DEBUG"`
+	Endpoint *url.URL          `env:"ENDPOINT" required:"true"`
+	Level    slog.Level        `env:"LEVEL" default:"info"`
+	Limits   *struct {
+		Max uint16 `env:"MAX" default:"10"`
+	} `env:"LIMIT_"`
+}
+
+func TestLoad(t *testing.T) {
+	envVars := []string{"APP_CONFIG_FILE", "APP_ENV", "TEST_BILLING_CURRENCY", "TEST_BILLING_RETRIES", "TEST_SHIPPING_CARRIERS", "TEST_LABELS", "TEST_TIMEOUT", "TEST_DEBUG", "TEST_ENDPOINT", "TEST_LEVEL", "TEST_LIMIT_MAX"}
+	original := make(map[string]string)
+	for _, name := range envVars {
+		original[name] = os.Getenv(name)
+	}
+	defer func() {
+		for name, value := range original {
+			os.Setenv(name, value)
+		}
+	}()
+	clearEnv := func() {
+		for _, name := range envVars {
+			os.Unsetenv(name)
+		}
+	}
+
+	t.Run("defaults and environment variables", func(t *testing.T) {
+		clearEnv()
+		os.Setenv("TEST_BILLING_RETRIES", "5")
+		os.Setenv("TEST_LABELS", "team=payments, tier=gold")
+		os.Setenv("TEST_DEBUG", "true")
+		os.Setenv("TEST_ENDPOINT", "https://billing.internal:8001/api")
+		os.Setenv("TEST_LEVEL", "debug")
+
+		s, err := Load[testSettings](WithPrefix("TEST_"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if s.Billing.Currency != "USD" || s.Billing.Retries != 5 {
+			t.Errorf("unexpected billing settings %+v", s.Billing)
+		}
+		if !slices.Equal(s.Carriers, []string{"ups", "fedex"}) {
+			t.Errorf("expected default carriers, got %v", s.Carriers)
+		}
+		if !maps.Equal(s.Labels, map[string]string{"team": "payments", "tier": "gold"}) {
+			t.Errorf("unexpected labels %v", s.Labels)
+		}
+		if s.Weights != nil {
+			t.Errorf("expected untagged field to be left unset, got %v", s.Weights)
+		}
+		if s.Timeout != 5*time.Second || !s.Debug || s.Level != slog.LevelDebug {
+			t.Errorf("unexpected settings %+v", s)
+		}
+		if s.Endpoint == nil || s.Endpoint.Host != "billing.internal:8001" {
+			t.Errorf("unexpected endpoint %v", s.Endpoint)
+		}
+		if s.Limits == nil || s.Limits.Max != 10 {
+			t.Errorf("expected nested pointer struct to be loaded, got %+v", s.Limits)
+		}
+	})
+
+	t.Run("config file", func(t *testing.T) {
+		clearEnv()
+		os.Setenv("TEST_SHIPPING_CARRIERS", "dhl")
+
+		file := filepath.Join(t.TempDir(), "settings.yaml")
+		data := "test:\n  billing:\n    currency: EUR\n  shipping:\n    carriers: [ups]\n  endpoint: https://example.com\n  limit:\n    max: 20\n"
+		if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+			t.Fatalf("failed to write config file: %v", err)
+		}
+
+		s, err := Load[testSettings](WithPrefix("TEST_"), FromFile(file))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if s.Billing.Currency != "EUR" || s.Limits.Max != 20 {
+			t.Errorf("expected values from the file, got %+v", s)
+		}
+		if !slices.Equal(s.Carriers, []string{"dhl"}) {
+			t.Errorf("expected the environment to override the file, got %v", s.Carriers)
+		}
+	})
+
+	t.Run("reports every problem", func(t *testing.T) {
+		clearEnv()
+		os.Setenv("TEST_BILLING_RETRIES", "many")
+		os.Setenv("TEST_LABELS", "team")
+		os.Setenv("TEST_TIMEOUT", "5")
+		os.Setenv("TEST_LIMIT_MAX", "70000")
+
+		_, err := Load[testSettings](WithPrefix("TEST_"))
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		for _, key := range []string{"TEST_BILLING_RETRIES", "TEST_LABELS", "TEST_TIMEOUT", "TEST_ENDPOINT", "TEST_LIMIT_MAX"} {
+			if !strings.Contains(err.Error(), key) {
+				t.Errorf("expected error to mention %s, got: %v", key, err)
+			}
+		}
+		if !errors.Is(err, ErrRequired) {
+			t.Errorf("expected error to wrap ErrRequired, got: %v", err)
+		}
+		var fieldErr *FieldError
+		if !errors.As(err, &fieldErr) {
+			t.Errorf("expected a FieldError, got %T", err)
+		}
+	})
+
+	t.Run("relative URL", func(t *testing.T) {
+		clearEnv()
+		os.Setenv("TEST_ENDPOINT", "/api")
+
+		if _, err := Load[testSettings](WithPrefix("TEST_")); err == nil || !strings.Contains(err.Error(), "TEST_ENDPOINT") {
+			t.Errorf("expected error for a relative URL, got: %v", err)
+		}
+	})
+
+	t.Run("not a struct", func(t *testing.T) {
+		if _, err := Load[string](); err == nil {
+			t.Error("expected error loading into a string")
+		}
+	})
+}
@@ -0,0 +1,213 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/z0mbix/go-microservices-monorepo/pkg/logger"
+)
+
+const defaultSecretReloadInterval = 5 * time.Second
+
+// Secret is a sensitive configuration value, such as a password or API key.
+// It prints and logs as [REDACTED], so only Value reveals it.
+//
+// Load resolves Secret fields from references rather than plain values:
+//
+//	file:///run/secrets/db_password  the contents of a file, without the
+//	                                 trailing newline, re-read when the file
+//	                                 is rotated
+//	env://DB_PASSWORD                another environment variable
+//	enc:<base64>                     a value encrypted by EncryptSecret with
+//	                                 the key in APP_SECRET_KEY_FILE
+//
+// Any other value is used as it is.
+type Secret struct {
+	// The value is held behind a pointer so printing a struct containing a
+	// Secret with %+v cannot show it, even in unexported fields
+	s *secret
+}
+
+type secret struct {
+	value string
+	// path is the file the value was read from, checked for rotation at most
+	// once per interval
+	path     string
+	interval time.Duration
+
+	mu          sync.Mutex
+	modTime     time.Time
+	lastChecked time.Time
+}
+
+// NewSecret returns a Secret holding value
+func NewSecret(value string) Secret {
+	return Secret{s: &secret{value: value}}
+}
+
+// Value returns the secret value. Secrets read from a file are re-read when
+// the file changes, keeping the current value if it can't be read.
+func (s Secret) Value() string {
+	if s.s == nil {
+		return ""
+	}
+	if s.s.path == "" {
+		return s.s.value
+	}
+
+	s.s.mu.Lock()
+	defer s.s.mu.Unlock()
+	if time.Since(s.s.lastChecked) >= s.s.interval {
+		s.s.lastChecked = time.Now()
+		if info, err := os.Stat(s.s.path); err == nil && !info.ModTime().Equal(s.s.modTime) {
+			if value, err := readSecretFile(s.s.path); err == nil {
+				s.s.value, s.s.modTime = value, info.ModTime()
+			}
+		}
+	}
+	return s.s.value
+}
+
+func (s Secret) String() string {
+	return logger.Redacted
+}
+
+// GoString keeps the value out of %#v
+func (s Secret) GoString() string {
+	return logger.Redacted
+}
+
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(logger.Redacted)
+}
+
+// MarshalText keeps the value out of JSON and other encodings
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(logger.Redacted), nil
+}
+
+// resolveSecret returns the Secret referred to by ref, reading the key for
+// encrypted values with key when needed
+func resolveSecret(ref string, key func() ([]byte, error)) (Secret, error) {
+	switch {
+	case strings.HasPrefix(ref, "file://"):
+		u, err := url.Parse(ref)
+		if err != nil {
+			return Secret{}, err
+		}
+		if u.Host != "" {
+			return Secret{}, fmt.Errorf("secret file must be an absolute path, e.g. file:///run/secrets/name")
+		}
+		info, err := os.Stat(u.Path)
+		if err != nil {
+			return Secret{}, fmt.Errorf("reading secret file: %w", err)
+		}
+		value, err := readSecretFile(u.Path)
+		if err != nil {
+			return Secret{}, err
+		}
+		return Secret{s: &secret{
+			value:       value,
+			path:        u.Path,
+			interval:    defaultSecretReloadInterval,
+			modTime:     info.ModTime(),
+			lastChecked: time.Now(),
+		}}, nil
+
+	case strings.HasPrefix(ref, "env://"):
+		name := strings.TrimPrefix(ref, "env://")
+		value := os.Getenv(name)
+		if value == "" {
+			return Secret{}, fmt.Errorf("secret environment variable %s is not set", name)
+		}
+		return NewSecret(value), nil
+
+	case strings.HasPrefix(ref, "enc:"):
+		k, err := key()
+		if err != nil {
+			return Secret{}, err
+		}
+		value, err := decryptSecret(k, strings.TrimPrefix(ref, "enc:"))
+		if err != nil {
+			return Secret{}, err
+		}
+		return NewSecret(value), nil
+
+	default:
+		return NewSecret(ref), nil
+	}
+}
+
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading secret file: %w", err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// readSecretKey reads a base64 encoded AES key, of 16, 24 or 32 bytes
+func readSecretKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading secret key: %w", err)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("secret key in %s is not base64 encoded: %w", path, err)
+	}
+	return key, nil
+}
+
+// EncryptSecret encrypts value with AES-GCM using key, which must be 16, 24
+// or 32 bytes, returning an enc: reference for Load to decrypt
+func EncryptSecret(key []byte, value string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(value), nil)
+	return "enc:" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func decryptSecret(key []byte, encoded string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("encrypted secret is not base64 encoded: %w", err)
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("encrypted secret is too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	value, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", errors.New("decrypting secret: wrong key or corrupted value")
+	}
+	return string(value), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secret key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
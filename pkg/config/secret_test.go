@@ -0,0 +1,180 @@
+package config
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSecretRedaction(t *testing.T) {
+	s := NewSecret("hunter2")
+	if s.Value() != "hunter2" {
+		t.Errorf("expected Value to reveal the secret, got %q", s.Value())
+	}
+
+	type settings struct {
+		Password Secret
+		token    Secret
+	}
+	v := settings{Password: s, token: s}
+
+	var buf bytes.Buffer
+	slog.New(slog.NewJSONHandler(&buf, nil)).Info("loaded", "password", s, "settings", v)
+	data, _ := json.Marshal(v)
+
+	outputs := map[string]string{
+		"String": s.String(),
+		"%v":     fmt.Sprintf("%v", v),
+		"%+v":    fmt.Sprintf("%+v", v),
+		"%#v":    fmt.Sprintf("%#v", s),
+		"JSON":   string(data),
+		"log":    buf.String(),
+	}
+	for name, output := range outputs {
+		if strings.Contains(output, "hunter2") {
+			t.Errorf("expected %s to redact the secret, got %s", name, output)
+		}
+	}
+	if !strings.Contains(buf.String(), `"password":"[REDACTED]"`) {
+		t.Errorf("expected the secret to be logged as [REDACTED], got %s", buf.String())
+	}
+}
+
+type testSecrets struct {
+	Password Secret `env:"DB_PASSWORD" required:"true"`
+	APIKey   Secret `env:"API_KEY"`
+	Token    Secret `env:"TOKEN"`
+	Plain    Secret `env:"PLAIN" default:"local-dev"`
+}
+
+func TestLoadSecrets(t *testing.T) {
+	envVars := []string{"APP_CONFIG_FILE", "APP_SECRET_KEY_FILE", "TEST_DB_PASSWORD", "TEST_API_KEY", "TEST_TOKEN", "TEST_PLAIN", "TEST_UPSTREAM_TOKEN"}
+	original := make(map[string]string)
+	for _, name := range envVars {
+		original[name] = os.Getenv(name)
+	}
+	defer func() {
+		for name, value := range original {
+			os.Setenv(name, value)
+		}
+	}()
+	clearEnv := func() {
+		for _, name := range envVars {
+			os.Unsetenv(name)
+		}
+	}
+
+	dir := t.TempDir()
+	passwordFile := filepath.Join(dir, "db_password")
+	if err := os.WriteFile(passwordFile, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatalf("failed to write secret: %v", err)
+	}
+	key := []byte("0123456789abcdef0123456789abcdef")
+	keyFile := filepath.Join(dir, "secret.key")
+	if err := os.WriteFile(keyFile, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0o600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	encrypted, err := EncryptSecret(key, "api-key-123")
+	if err != nil {
+		t.Fatalf("failed to encrypt secret: %v", err)
+	}
+
+	t.Run("resolves references", func(t *testing.T) {
+		clearEnv()
+		os.Setenv("APP_SECRET_KEY_FILE", keyFile)
+		os.Setenv("TEST_DB_PASSWORD", "file://"+passwordFile)
+		os.Setenv("TEST_API_KEY", encrypted)
+		os.Setenv("TEST_TOKEN", "env://TEST_UPSTREAM_TOKEN")
+		os.Setenv("TEST_UPSTREAM_TOKEN", "upstream-token")
+
+		s, err := Load[testSecrets](WithPrefix("TEST_"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		values := []struct {
+			name      string
+			got, want string
+		}{
+			{"file", s.Password.Value(), "s3cret"},
+			{"encrypted", s.APIKey.Value(), "api-key-123"},
+			{"env", s.Token.Value(), "upstream-token"},
+			{"plain", s.Plain.Value(), "local-dev"},
+		}
+		for _, v := range values {
+			if v.got != v.want {
+				t.Errorf("expected %s secret %q, got %q", v.name, v.want, v.got)
+			}
+		}
+
+		// The rotated file is read once the reload interval has passed
+		if err := os.WriteFile(passwordFile, []byte("rotated\n"), 0o600); err != nil {
+			t.Fatalf("failed to rotate secret: %v", err)
+		}
+		os.Chtimes(passwordFile, time.Now(), time.Now().Add(time.Second))
+		if got := s.Password.Value(); got != "s3cret" {
+			t.Errorf("expected the file not to be checked again before the interval, got %q", got)
+		}
+		s.Password.s.interval = 0
+		if got := s.Password.Value(); got != "rotated" {
+			t.Errorf("expected the rotated secret, got %q", got)
+		}
+
+		os.Remove(passwordFile)
+		if got := s.Password.Value(); got != "rotated" {
+			t.Errorf("expected the current secret to be kept when the file is missing, got %q", got)
+		}
+	})
+
+	t.Run("key option", func(t *testing.T) {
+		clearEnv()
+		os.Setenv("TEST_DB_PASSWORD", encrypted)
+
+		s, err := Load[testSecrets](WithPrefix("TEST_"), WithSecretKey(key))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if s.Password.Value() != "api-key-123" {
+			t.Errorf("expected the decrypted secret, got %q", s.Password.Value())
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		clearEnv()
+		wrongKey, _ := EncryptSecret([]byte("fedcba9876543210fedcba9876543210"), "other")
+		os.Setenv("APP_SECRET_KEY_FILE", keyFile)
+		os.Setenv("TEST_DB_PASSWORD", "file://"+filepath.Join(dir, "missing"))
+		os.Setenv("TEST_API_KEY", wrongKey)
+		os.Setenv("TEST_TOKEN", "env://TEST_UPSTREAM_TOKEN")
+
+		_, err := Load[testSecrets](WithPrefix("TEST_"))
+		if err == nil {
+			t.Fatal("expected error but got nil")
+		}
+		for _, want := range []string{
+			"TEST_DB_PASSWORD: invalid secret: reading secret file",
+			"TEST_API_KEY: invalid secret: decrypting secret: wrong key",
+			"TEST_TOKEN: invalid secret: secret environment variable TEST_UPSTREAM_TOKEN is not set",
+		} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("expected error to contain %q, got:\n%v", want, err)
+			}
+		}
+	})
+
+	t.Run("missing key", func(t *testing.T) {
+		clearEnv()
+		os.Setenv("TEST_DB_PASSWORD", encrypted)
+
+		_, err := Load[testSecrets](WithPrefix("TEST_"))
+		if err == nil || !strings.Contains(err.Error(), "APP_SECRET_KEY_FILE") {
+			t.Errorf("expected error naming APP_SECRET_KEY_FILE, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,331 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Layer identifies where a configuration value came from. Later layers take
+// precedence over earlier ones.
+type Layer int
+
+const (
+	LayerDefault Layer = iota
+	// LayerFile is the file named by APP_CONFIG_FILE
+	LayerFile
+	// LayerEnvironmentFile is the override of the config file for the
+	// environment, e.g. config.production.yaml
+	LayerEnvironmentFile
+	LayerEnv
+	LayerFlag
+	// LayerOption is a value set by an Option passed to New
+	LayerOption
+)
+
+func (l Layer) String() string {
+	switch l {
+	case LayerDefault:
+		return "default"
+	case LayerFile:
+		return "file"
+	case LayerEnvironmentFile:
+		return "environment file"
+	case LayerEnv:
+		return "env"
+	case LayerFlag:
+		return "flag"
+	case LayerOption:
+		return "option"
+	default:
+		return "unknown"
+	}
+}
+
+// Source is the effective value of a setting and where it came from
+type Source struct {
+	// Key is the environment variable setting the value, e.g. APP_PORT
+	Key   string
+	Value string
+	Layer Layer
+	// File is the config file the value was read from, for file layers
+	File string
+}
+
+func (s Source) String() string {
+	if s.File != "" {
+		return fmt.Sprintf("%s=%s (%s %s)", s.Key, s.Value, s.Layer, s.File)
+	}
+	return fmt.Sprintf("%s=%s (%s)", s.Key, s.Value, s.Layer)
+}
+
+// describe names the setting and its source for error messages
+func (s Source) describe() string {
+	switch s.Layer {
+	case LayerEnv:
+		return s.Key + " environment variable"
+	case LayerFlag:
+		return fmt.Sprintf("%s (flag -%s)", s.Key, flagName(s.Key))
+	case LayerFile, LayerEnvironmentFile:
+		return fmt.Sprintf("%s (config file %s)", s.Key, s.File)
+	default:
+		return s.Key
+	}
+}
+
+// Sources reports the effective value of every setting and the layer it came
+// from, sorted by key
+func (c *Config) Sources() []Source {
+	sources := make([]Source, 0, len(settings))
+	for _, s := range settings {
+		sources = append(sources, c.source(s))
+	}
+	slices.SortFunc(sources, func(a, b Source) int { return strings.Compare(a.Key, b.Key) })
+	return sources
+}
+
+// source returns the effective value of s and where it came from
+func (c *Config) source(s setting) Source {
+	value := s.get(c)
+	src, ok := c.sources[s.key]
+	if !ok {
+		src = Source{Key: s.key, Value: value, Layer: LayerDefault}
+	}
+	if src.Value != value {
+		src = Source{Key: s.key, Value: value, Layer: LayerOption}
+	}
+	return src
+}
+
+// Lookup returns the value of a variable which is not one of the settings of
+// Config, e.g. APP_SERVICE_BILLING_URL, from the environment or the config
+// files, which may set it without the APP_ prefix, e.g. service.billing.url
+func (c *Config) Lookup(key string) (Source, bool) {
+	if c.loader == nil {
+		value := os.Getenv(key)
+		return Source{Key: key, Value: value, Layer: LayerEnv}, value != ""
+	}
+	_, src, ok := c.loader.lookup(key)
+	return src, ok
+}
+
+// describe names the setting key and where its value came from for error
+// messages
+func (c *Config) describe(key string) string {
+	for _, s := range settings {
+		if s.key == key {
+			return c.source(s).describe()
+		}
+	}
+	return key
+}
+
+// setting describes a configuration value, named by its environment variable
+type setting struct {
+	key   string
+	usage string
+	def   string
+	kind  string // what set expects, used in error messages
+	// manual settings are only resolved by an Option, e.g. WithDefaultPort
+	manual bool
+	set    func(c *Config, value string) error
+	get    func(c *Config) string
+}
+
+var settings = []setting{
+	stringSetting("APP_CONFIG_FILE", "YAML, JSON or TOML config file", "", func(c *Config) *string { return &c.File }),
+	stringSetting("APP_ENV", "Environment (local, development, test, staging, production)", "local", func(c *Config) *string { return &c.Environment }),
+	listSetting("APP_FEATURES", "Comma separated feature flags to enable", func(c *Config) *[]string { return &c.Features }),
+	stringSetting("APP_FEATURE_FLAGS_FILE", "YAML, JSON or TOML file defining feature flags", "", func(c *Config) *string { return &c.FeatureFlagsFile }),
+	manual(intSetting("APP_PORT", "HTTP server port", 0, func(c *Config) *int { return &c.Port })),
+	intSetting("APP_ADMIN_PORT", "Separate port for the operational endpoints", 0, func(c *Config) *int { return &c.AdminPort }),
+	stringSetting("APP_LOG_LEVEL", "Logging level (debug, info, warn, error)", "info", func(c *Config) *string { return &c.LogLevel }),
+	stringSetting("APP_LOG_FORMAT", "Log format (json, text, pretty)", "json", func(c *Config) *string { return &c.LogFormat }),
+	stringSetting("APP_LOG_OUTPUT", "Log destination (stdout, stderr, file:///path, syslog)", "stdout", func(c *Config) *string { return &c.LogOutput }),
+	durationSetting("APP_HTTP_READ_HEADER_TIMEOUT", "Time allowed to read request headers", DefaultReadHeaderTimeout, func(c *Config) *time.Duration { return &c.HTTP.ReadHeaderTimeout }),
+	durationSetting("APP_HTTP_READ_TIMEOUT", "Time allowed to read the whole request", DefaultReadTimeout, func(c *Config) *time.Duration { return &c.HTTP.ReadTimeout }),
+	durationSetting("APP_HTTP_WRITE_TIMEOUT", "Time allowed to write the response", DefaultWriteTimeout, func(c *Config) *time.Duration { return &c.HTTP.WriteTimeout }),
+	durationSetting("APP_HTTP_IDLE_TIMEOUT", "Time keep-alive connections may stay idle", DefaultIdleTimeout, func(c *Config) *time.Duration { return &c.HTTP.IdleTimeout }),
+	intSetting("APP_HTTP_MAX_HEADER_BYTES", "Maximum size of request headers", DefaultMaxHeaderBytes, func(c *Config) *int { return &c.HTTP.MaxHeaderBytes }),
+	intSetting("APP_HTTP_MAX_CONNECTIONS", "Maximum concurrent connections (0 for no limit)", 0, func(c *Config) *int { return &c.HTTP.MaxConnections }),
+	stringSetting("APP_SERVICE_REGISTRY_FILE", "YAML, JSON or TOML file listing service endpoints", "", func(c *Config) *string { return &c.ServiceRegistryFile }),
+	stringSetting("APP_TLS_CERT_FILE", "TLS certificate, enables HTTPS when set with the key", "", func(c *Config) *string { return &c.TLS.CertFile }),
+	stringSetting("APP_TLS_KEY_FILE", "TLS private key", "", func(c *Config) *string { return &c.TLS.KeyFile }),
+	stringSetting("APP_TLS_CLIENT_CA_FILE", "CA bundle used to require and verify client certificates", "", func(c *Config) *string { return &c.TLS.ClientCAFile }),
+}
+
+// manual marks a setting as only resolved by an Option
+func manual(s setting) setting {
+	s.manual = true
+	return s
+}
+
+func stringSetting(key, usage, def string, field func(*Config) *string) setting {
+	return setting{
+		key: key, usage: usage, def: def, kind: "value",
+		set: func(c *Config, value string) error {
+			*field(c) = value
+			return nil
+		},
+		get: func(c *Config) string { return *field(c) },
+	}
+}
+
+func intSetting(key, usage string, def int, field func(*Config) *int) setting {
+	return setting{
+		key: key, usage: usage, def: strconv.Itoa(def), kind: "integer",
+		set: func(c *Config, value string) error {
+			i, err := strconv.Atoi(value)
+			if err != nil {
+				return err
+			}
+			*field(c) = i
+			return nil
+		},
+		get: func(c *Config) string { return strconv.Itoa(*field(c)) },
+	}
+}
+
+func durationSetting(key, usage string, def time.Duration, field func(*Config) *time.Duration) setting {
+	return setting{
+		key: key, usage: usage, def: def.String(), kind: "duration",
+		set: func(c *Config, value string) error {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return err
+			}
+			*field(c) = d
+			return nil
+		},
+		get: func(c *Config) string { return field(c).String() },
+	}
+}
+
+func listSetting(key, usage string, field func(*Config) *[]string) setting {
+	return setting{
+		key: key, usage: usage, kind: "list",
+		set: func(c *Config, value string) error {
+			*field(c) = splitList(value)
+			return nil
+		},
+		get: func(c *Config) string { return strings.Join(*field(c), ",") },
+	}
+}
+
+// loader looks up settings in the config files, environment and flags
+type loader struct {
+	flags         map[string]string
+	file          string
+	fileValues    map[string]string
+	envFile       string
+	envFileValues map[string]string
+}
+
+func newLoader(args []string) (*loader, error) {
+	flags, err := parseFlags(args)
+	if err != nil {
+		return nil, err
+	}
+	l := &loader{flags: flags}
+
+	if path, _, ok := l.lookup("APP_CONFIG_FILE"); ok {
+		if err := l.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// loadFile reads the config file at path and its override for the
+// environment, if there is one
+func (l *loader) loadFile(path string) error {
+	values, err := ReadFile(path)
+	if err != nil {
+		return err
+	}
+	l.file, l.fileValues = path, values
+
+	environment, _, ok := l.lookup("APP_ENV")
+	if !ok {
+		environment = "local"
+	}
+	envFile := environmentFile(path, environment)
+	values, err = ReadFile(envFile)
+	switch {
+	case err == nil:
+		l.envFile, l.envFileValues = envFile, values
+	case !errors.Is(err, fs.ErrNotExist):
+		return err
+	}
+	return nil
+}
+
+// lookup returns the value of the setting named key from the highest
+// priority layer which sets it. Config files may set it either by its full
+// name or without the APP_ prefix, e.g. http.read_timeout.
+func (l *loader) lookup(key string) (string, Source, bool) {
+	if value, ok := l.flags[key]; ok {
+		return value, Source{Key: key, Value: value, Layer: LayerFlag}, true
+	}
+	if value := os.Getenv(key); value != "" {
+		return value, Source{Key: key, Value: value, Layer: LayerEnv}, true
+	}
+
+	files := []struct {
+		layer  Layer
+		path   string
+		values map[string]string
+	}{
+		{LayerEnvironmentFile, l.envFile, l.envFileValues},
+		{LayerFile, l.file, l.fileValues},
+	}
+	for _, f := range files {
+		for _, name := range []string{strings.TrimPrefix(key, "APP_"), key} {
+			if value, ok := f.values[name]; ok {
+				return value, Source{Key: key, Value: value, Layer: f.layer, File: f.path}, true
+			}
+		}
+	}
+
+	return "", Source{}, false
+}
+
+// parseFlags parses a flag for every setting, e.g. -http-read-timeout for
+// APP_HTTP_READ_TIMEOUT, returning the values of those which were set
+func parseFlags(args []string) (map[string]string, error) {
+	fs := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ContinueOnError)
+	keys := make(map[string]string, len(settings))
+	for _, s := range settings {
+		usage := fmt.Sprintf("%s (%s)", s.usage, s.key)
+		if s.def != "" && !s.manual {
+			usage = fmt.Sprintf("%s (%s, default %s)", s.usage, s.key, s.def)
+		}
+		fs.String(flagName(s.key), "", usage)
+		keys[flagName(s.key)] = s.key
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	values := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		values[keys[f.Name]] = f.Value.String()
+	})
+	return values, nil
+}
+
+// flagName returns the command line flag for an environment variable
+func flagName(key string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimPrefix(key, "APP_")), "_", "-")
+}
@@ -0,0 +1,170 @@
+package config
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Environments are the valid values of APP_ENV
+var Environments = []string{"local", "development", "test", "staging", "production"}
+
+// Validator is implemented by configuration with rules that cannot be
+// expressed with tags, e.g. fields that must be set together. Load calls
+// Validate on the struct it populates and on any nested structs.
+type Validator interface {
+	Validate() error
+}
+
+// Validate checks every setting, returning an error which lists each invalid
+// value and where it was set. New and NewWithArgs call it once the options
+// have been applied.
+func (c *Config) Validate() error {
+	return errors.Join(c.problems(nil)...)
+}
+
+// problems returns an error for each invalid setting, except those in skip
+// which are already known to be invalid
+func (c *Config) problems(skip map[string]bool) []error {
+	checks := []struct {
+		key   string
+		value any
+		rules string
+	}{
+		{"APP_PORT", c.Port, "min=0,max=65535"},
+		{"APP_ADMIN_PORT", c.AdminPort, "min=0,max=65535"},
+		{"APP_ENV", c.Environment, "oneof=" + strings.Join(Environments, " ")},
+		{"APP_LOG_LEVEL", strings.ToLower(c.LogLevel), "oneof=debug info warn error"},
+		{"APP_LOG_FORMAT", strings.ToLower(c.LogFormat), "oneof=json text pretty"},
+	}
+
+	var errs []error
+	for _, check := range checks {
+		if skip[check.key] {
+			continue
+		}
+		for _, err := range checkRules(reflect.ValueOf(check.value), check.rules) {
+			errs = append(errs, fmt.Errorf("invalid value in %s: %w", c.describe(check.key), err))
+		}
+	}
+	errs = append(errs, c.HTTP.validate(skip)...)
+	return append(errs, c.TLS.validate()...)
+}
+
+// checkRules checks v against rules, the contents of a validate tag, see Load
+func checkRules(v reflect.Value, rules string) []error {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	var errs []error
+	for rule := range strings.SplitSeq(rules, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		var err error
+		switch name {
+		case "":
+			continue
+		case "min":
+			err = checkBound(v, arg, -1)
+		case "max":
+			err = checkBound(v, arg, 1)
+		case "oneof":
+			options := strings.Fields(arg)
+			if !slices.Contains(options, fmt.Sprint(v.Interface())) {
+				err = fmt.Errorf("must be one of %s, got %q", strings.Join(options, ", "), fmt.Sprint(v.Interface()))
+			}
+		case "url":
+			err = checkURL(v)
+		default:
+			err = fmt.Errorf("unknown validation rule %q", name)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// checkBound checks v is not beyond limit, below it when sign is -1 or above
+// it when sign is 1
+func checkBound(v reflect.Value, limit string, sign int) error {
+	var order int
+	var got any
+	var err error
+	length := false
+
+	switch {
+	case v.Type() == durationType:
+		var d time.Duration
+		if d, err = time.ParseDuration(limit); err == nil {
+			got = time.Duration(v.Int())
+			order = cmp.Compare(time.Duration(v.Int()), d)
+		}
+	case v.CanInt():
+		var i int64
+		if i, err = strconv.ParseInt(limit, 10, 64); err == nil {
+			got, order = v.Int(), cmp.Compare(v.Int(), i)
+		}
+	case v.CanUint():
+		var u uint64
+		if u, err = strconv.ParseUint(limit, 10, 64); err == nil {
+			got, order = v.Uint(), cmp.Compare(v.Uint(), u)
+		}
+	case v.CanFloat():
+		var f float64
+		if f, err = strconv.ParseFloat(limit, 64); err == nil {
+			got, order = v.Float(), cmp.Compare(v.Float(), f)
+		}
+	case v.Kind() == reflect.String, v.Kind() == reflect.Slice, v.Kind() == reflect.Map:
+		var n int
+		if n, err = strconv.Atoi(limit); err == nil {
+			got, order, length = v.Len(), cmp.Compare(v.Len(), n), true
+		}
+	default:
+		return fmt.Errorf("cannot check the bounds of a %s", v.Type())
+	}
+	if err != nil {
+		return fmt.Errorf("invalid limit %q: %w", limit, err)
+	}
+	if order != sign {
+		return nil
+	}
+
+	bound := "at least"
+	if sign > 0 {
+		bound = "at most"
+	}
+	if length {
+		return fmt.Errorf("must have a length of %s %s, got %v", bound, limit, got)
+	}
+	return fmt.Errorf("must be %s %s, got %v", bound, limit, got)
+}
+
+func checkURL(v reflect.Value) error {
+	var u *url.URL
+	switch {
+	case v.Type() == urlType:
+		u = new(url.URL)
+		*u = v.Interface().(url.URL)
+	case v.Kind() == reflect.String:
+		var err error
+		if u, err = url.Parse(v.String()); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("cannot check a %s is a URL", v.Type())
+	}
+	if !u.IsAbs() || u.Host == "" {
+		return fmt.Errorf("must be an absolute URL, got %q", u.String())
+	}
+	return nil
+}
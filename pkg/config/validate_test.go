@@ -0,0 +1,178 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	envVars := []string{"APP_CONFIG_FILE", "APP_PORT", "APP_ENV", "APP_LOG_LEVEL", "APP_LOG_FORMAT", "APP_HTTP_READ_TIMEOUT", "APP_TLS_KEY_FILE"}
+	original := make(map[string]string)
+	for _, name := range envVars {
+		original[name] = os.Getenv(name)
+	}
+	defer func() {
+		for name, value := range original {
+			os.Setenv(name, value)
+		}
+	}()
+	clearEnv := func() {
+		for _, name := range envVars {
+			os.Unsetenv(name)
+		}
+	}
+
+	t.Run("reports every problem", func(t *testing.T) {
+		clearEnv()
+		os.Setenv("APP_PORT", "99999")
+		os.Setenv("APP_ENV", "qa")
+		os.Setenv("APP_LOG_LEVEL", "trace")
+		os.Setenv("APP_HTTP_READ_TIMEOUT", "soon")
+		os.Setenv("APP_TLS_KEY_FILE", "key.pem")
+
+		_, err := NewWithArgs([]string{"-log-format", "xml"}, WithDefaultPort(8001))
+		if err == nil {
+			t.Fatal("expected error but got nil")
+		}
+		for _, want := range []string{
+			"APP_PORT environment variable: must be at most 65535, got 99999",
+			"APP_ENV environment variable: must be one of local, development, test, staging, production",
+			"APP_LOG_LEVEL environment variable",
+			"APP_LOG_FORMAT (flag -log-format)",
+			"APP_HTTP_READ_TIMEOUT environment variable",
+			"APP_TLS_CERT_FILE and APP_TLS_KEY_FILE must be set together",
+		} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("expected error to contain %q, got:\n%v", want, err)
+			}
+		}
+		if strings.Contains(err.Error(), "greater than zero") {
+			t.Errorf("expected values which could not be parsed not to be validated, got:\n%v", err)
+		}
+	})
+
+	t.Run("levels and formats are case insensitive", func(t *testing.T) {
+		clearEnv()
+		os.Setenv("APP_LOG_LEVEL", "DEBUG")
+		os.Setenv("APP_LOG_FORMAT", "Pretty")
+
+		if _, err := New(); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("after options", func(t *testing.T) {
+		clearEnv()
+		cfg, err := New()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		cfg.AdminPort = -1
+		err = cfg.Validate()
+		if err == nil || !strings.Contains(err.Error(), "APP_ADMIN_PORT: must be at least 0") {
+			t.Errorf("expected error naming APP_ADMIN_PORT, got %v", err)
+		}
+	})
+}
+
+type testShipping struct {
+	Carriers []string      `env:"CARRIERS" default:"ups" validate:"min=1,max=3"`
+	Currency string        `env:"CURRENCY" default:"USD" validate:"oneof=USD EUR GBP"`
+	Port     int           `env:"PORT" default:"8002" validate:"min=1,max=65535"`
+	Timeout  time.Duration `env:"TIMEOUT" default:"5s" validate:"min=1s,max=1m"`
+	Tracking string        `env:"TRACKING_URL" validate:"url"`
+	Retries  *uint         `env:"RETRIES" validate:"max=5"`
+}
+
+func (s *testShipping) Validate() error {
+	if s.Currency != "USD" && s.Tracking == "" {
+		return errors.New("TEST_TRACKING_URL is required outside the US")
+	}
+	return nil
+}
+
+func TestLoadValidation(t *testing.T) {
+	envVars := []string{"APP_CONFIG_FILE", "TEST_CARRIERS", "TEST_CURRENCY", "TEST_PORT", "TEST_TIMEOUT", "TEST_TRACKING_URL", "TEST_RETRIES"}
+	original := make(map[string]string)
+	for _, name := range envVars {
+		original[name] = os.Getenv(name)
+	}
+	defer func() {
+		for name, value := range original {
+			os.Setenv(name, value)
+		}
+	}()
+	clearEnv := func() {
+		for _, name := range envVars {
+			os.Unsetenv(name)
+		}
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		clearEnv()
+		os.Setenv("TEST_RETRIES", "5")
+		s, err := Load[testShipping](WithPrefix("TEST_"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if *s.Retries != 5 {
+			t.Errorf("expected 5 retries, got %d", *s.Retries)
+		}
+	})
+
+	t.Run("tag rules", func(t *testing.T) {
+		clearEnv()
+		os.Setenv("TEST_CARRIERS", "ups,fedex,dhl,usps")
+		os.Setenv("TEST_CURRENCY", "JPY")
+		os.Setenv("TEST_PORT", "0")
+		os.Setenv("TEST_TIMEOUT", "2m")
+		os.Setenv("TEST_TRACKING_URL", "tracking")
+		os.Setenv("TEST_RETRIES", "6")
+
+		_, err := Load[testShipping](WithPrefix("TEST_"))
+		if err == nil {
+			t.Fatal("expected error but got nil")
+		}
+		for _, want := range []string{
+			"TEST_CARRIERS: must have a length of at most 3, got 4",
+			`TEST_CURRENCY: must be one of USD, EUR, GBP, got "JPY"`,
+			"TEST_PORT: must be at least 1, got 0",
+			"TEST_TIMEOUT: must be at most 1m, got 2m0s",
+			"TEST_TRACKING_URL: must be an absolute URL",
+			"TEST_RETRIES: must be at most 5, got 6",
+		} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("expected error to contain %q, got:\n%v", want, err)
+			}
+		}
+		if strings.Contains(err.Error(), "outside the US") {
+			t.Error("expected Validate not to be called when fields are invalid")
+		}
+	})
+
+	t.Run("Validate hook", func(t *testing.T) {
+		clearEnv()
+		os.Setenv("TEST_CURRENCY", "EUR")
+
+		_, err := Load[testShipping](WithPrefix("TEST_"))
+		if err == nil || !strings.Contains(err.Error(), "TEST_TRACKING_URL is required outside the US") {
+			t.Errorf("expected error from Validate, got %v", err)
+		}
+		var fe *FieldError
+		if !errors.As(err, &fe) || fe.Key != "testShipping" {
+			t.Errorf("expected a FieldError for the struct, got %#v", err)
+		}
+	})
+
+	t.Run("unknown rule", func(t *testing.T) {
+		type settings struct {
+			Name string `env:"NAME" default:"x" validate:"email"`
+		}
+		if _, err := Load[settings](); err == nil || !strings.Contains(err.Error(), "unknown validation rule") {
+			t.Errorf("expected error for an unknown rule, got %v", err)
+		}
+	})
+}
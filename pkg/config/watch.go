@@ -0,0 +1,149 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultWatchInterval = 5 * time.Second
+
+// watcher is shared by every Config loaded from the same arguments and
+// options, holding the current one and the change subscribers
+type watcher struct {
+	args     []string
+	opts     []Option
+	interval time.Duration
+	current  atomic.Pointer[Config]
+	// reload serialises reloads so subscribers see changes in order
+	reload sync.Mutex
+
+	mu          sync.Mutex
+	subscribers []func(old, new *Config)
+}
+
+// Current returns the most recently loaded Config, which is c until the
+// configuration is reloaded
+func (c *Config) Current() *Config {
+	if c.watch == nil {
+		return c
+	}
+	return c.watch.current.Load()
+}
+
+// OnChange registers fn to be called with the previous and new Config each
+// time the configuration is reloaded. Subscribers are called in the order
+// they were registered, one reload at a time, and must not call Reload.
+func (c *Config) OnChange(fn func(old, new *Config)) {
+	if c.watch == nil {
+		return
+	}
+	c.watch.mu.Lock()
+	defer c.watch.mu.Unlock()
+	c.watch.subscribers = append(c.watch.subscribers, fn)
+}
+
+// Reload loads the configuration again from the same layers, arguments and
+// options, and if it is valid makes it the Current one and notifies the
+// subscribers. On error the current configuration is kept. Environment
+// variables and flags cannot change while the process runs, so in practice
+// only the config files can change the result.
+func (c *Config) Reload() error {
+	w := c.watch
+	if w == nil {
+		return errors.New("configuration was not loaded by New and cannot be reloaded")
+	}
+
+	w.reload.Lock()
+	defer w.reload.Unlock()
+
+	next, err := NewWithArgs(w.args, w.opts...)
+	if err != nil {
+		return err
+	}
+	next.watch = w
+	old := w.current.Swap(next)
+
+	w.mu.Lock()
+	subscribers := append([]func(old, new *Config){}, w.subscribers...)
+	w.mu.Unlock()
+	for _, fn := range subscribers {
+		fn(old, next)
+	}
+	return nil
+}
+
+// Watch reloads the configuration when the config file, its override for the
+// environment or the feature flags file changes on disk, or when the process
+// receives SIGHUP, until ctx is cancelled. Files are checked every few
+// seconds. Reload errors are passed to onError, which may be nil, and leave
+// the current configuration in place.
+func (c *Config) Watch(ctx context.Context, onError func(error)) {
+	if c.watch == nil {
+		return
+	}
+	if onError == nil {
+		onError = func(error) {}
+	}
+
+	signals := make(chan os.Signal, 1)
+	if len(reloadSignals) > 0 {
+		signal.Notify(signals, reloadSignals...)
+	}
+
+	ticker := time.NewTicker(c.watch.interval)
+	files := c.Current().fileStates()
+
+	go func() {
+		defer ticker.Stop()
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-signals:
+			case <-ticker.C:
+				// Files which cannot be read are left for Reload to report
+				current := c.Current().fileStates()
+				if current == files {
+					continue
+				}
+				files = current
+			}
+
+			if err := c.Reload(); err != nil {
+				onError(err)
+			}
+		}
+	}()
+}
+
+// fileState is the modification time and size of a config file, zero when it
+// does not exist
+type fileState struct {
+	modTime int64
+	size    int64
+}
+
+// fileStates describes the config file, its override for the environment and
+// the feature flags file
+func (c *Config) fileStates() [3]fileState {
+	var states [3]fileState
+	paths := []string{c.File, "", c.FeatureFlagsFile}
+	if c.File != "" {
+		paths[1] = environmentFile(c.File, c.Environment)
+	}
+	for i, path := range paths {
+		if path == "" {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil {
+			states[i] = fileState{modTime: info.ModTime().UnixNano(), size: info.Size()}
+		}
+	}
+	return states
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package config
+
+import "os"
+
+// Reloading on SIGHUP is only supported on unix
+var reloadSignals []os.Signal
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReload(t *testing.T) {
+	envVars := []string{"APP_CONFIG_FILE", "APP_ENV", "APP_PORT", "APP_LOG_LEVEL", "APP_FEATURES", "APP_HTTP_READ_TIMEOUT"}
+	original := make(map[string]string)
+	for _, name := range envVars {
+		original[name] = os.Getenv(name)
+	}
+	defer func() {
+		for name, value := range original {
+			os.Setenv(name, value)
+		}
+	}()
+	for _, name := range envVars {
+		os.Unsetenv(name)
+	}
+
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig := func(data string) {
+		t.Helper()
+		if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", file, err)
+		}
+	}
+	writeConfig("log_level: info\nfeatures: [checkout]\n")
+	os.Setenv("APP_CONFIG_FILE", file)
+
+	cfg, err := New(WithDefaultPort(8001))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Current() != cfg {
+		t.Error("expected the loaded Config to be current")
+	}
+	if !slices.Equal(cfg.Features, []string{"checkout"}) {
+		t.Errorf("expected features from the file, got %v", cfg.Features)
+	}
+
+	type change struct{ old, new *Config }
+	changes := make(chan change, 10)
+	cfg.OnChange(func(old, new *Config) {
+		changes <- change{old, new}
+	})
+
+	t.Run("reload swaps and notifies", func(t *testing.T) {
+		writeConfig("log_level: debug\nfeatures: [checkout, refunds]\n")
+		if err := cfg.Reload(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		c := <-changes
+		if c.old != cfg || c.new != cfg.Current() {
+			t.Error("expected subscribers to get the previous and current Config")
+		}
+		if c.new.LogLevel != "debug" || !slices.Equal(c.new.Features, []string{"checkout", "refunds"}) {
+			t.Errorf("expected reloaded values, got %+v", c.new)
+		}
+		if c.new.Port != 8001 {
+			t.Errorf("expected options to be applied again, got port %d", c.new.Port)
+		}
+		if cfg.LogLevel != "info" {
+			t.Error("expected the previous Config to be unchanged")
+		}
+	})
+
+	t.Run("invalid file is not applied", func(t *testing.T) {
+		current := cfg.Current()
+		writeConfig("log_level: trace\nhttp:\n  read_timeout: 0s\n")
+
+		err := cfg.Reload()
+		if err == nil {
+			t.Fatal("expected error but got nil")
+		}
+		if !strings.Contains(err.Error(), "APP_LOG_LEVEL") || !strings.Contains(err.Error(), "APP_HTTP_READ_TIMEOUT") {
+			t.Errorf("expected every problem to be reported, got %v", err)
+		}
+		if cfg.Current() != current {
+			t.Error("expected the current Config to be kept")
+		}
+		select {
+		case <-changes:
+			t.Error("expected subscribers not to be notified")
+		default:
+		}
+	})
+
+	t.Run("watch", func(t *testing.T) {
+		cfg.watch.interval = 10 * time.Millisecond
+		errs := make(chan error, 10)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		cfg.Watch(ctx, func(err error) { errs <- err })
+
+		writeConfig("log_level: warn\n")
+		select {
+		case c := <-changes:
+			if c.new.LogLevel != "warn" {
+				t.Errorf("expected the changed file to be loaded, got %q", c.new.LogLevel)
+			}
+		case err := <-errs:
+			t.Fatalf("unexpected error: %v", err)
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for the file change to be loaded")
+		}
+
+		if len(reloadSignals) == 0 {
+			return
+		}
+		process, err := os.FindProcess(os.Getpid())
+		if err != nil {
+			t.Fatalf("failed to find process: %v", err)
+		}
+		process.Signal(reloadSignals[0])
+		select {
+		case <-changes:
+		case err := <-errs:
+			t.Fatalf("unexpected error: %v", err)
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for the reload signal")
+		}
+	})
+
+	t.Run("not loaded by New", func(t *testing.T) {
+		var c Config
+		if c.Current() != &c {
+			t.Error("expected a Config not loaded by New to be current")
+		}
+		if err := c.Reload(); err == nil {
+			t.Error("expected error reloading a Config not loaded by New")
+		}
+	})
+}
@@ -0,0 +1,11 @@
+//go:build unix
+
+package config
+
+import (
+	"os"
+	"syscall"
+)
+
+// SIGHUP makes Watch reload the configuration
+var reloadSignals = []os.Signal{syscall.SIGHUP}
@@ -0,0 +1,352 @@
+package discovery
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/z0mbix/go-microservices-monorepo/pkg/config"
+)
+
+const (
+	defaultCheckInterval = 10 * time.Second
+	defaultCheckTimeout  = 2 * time.Second
+)
+
+// Defaults are the endpoints of the monorepo services when they all run
+// locally on their default ports
+var Defaults = map[string]string{
+	"billing":  "http://localhost:8001",
+	"order":    "http://localhost:8002",
+	"shipping": "http://localhost:8003",
+	"user":     "http://localhost:8004",
+}
+
+// ErrUnknownService is returned for services which are not in the registry
+var ErrUnknownService = errors.New("unknown service")
+
+// Strategy chooses which endpoint of a service a call is sent to
+type Strategy int
+
+const (
+	// RoundRobin sends calls to each endpoint in turn
+	RoundRobin Strategy = iota
+	// LeastOutstanding sends calls to the endpoint with the fewest calls in
+	// progress, which favours faster instances
+	LeastOutstanding
+)
+
+// Endpoint is an instance of a service
+type Endpoint struct {
+	URL *url.URL
+	// HealthURL is where Watch checks the endpoint's readiness
+	HealthURL *url.URL
+
+	healthy     atomic.Bool
+	outstanding atomic.Int64
+}
+
+// Healthy reports whether the endpoint passed its last readiness check
+func (e *Endpoint) Healthy() bool {
+	return e.healthy.Load()
+}
+
+// Outstanding returns the number of calls to the endpoint in progress
+func (e *Endpoint) Outstanding() int64 {
+	return e.outstanding.Load()
+}
+
+// upstream is a logical service and its endpoints
+type upstream struct {
+	name      string
+	endpoints []*Endpoint
+	next      atomic.Uint64
+}
+
+// Registry resolves logical service names, such as billing, to endpoints
+type Registry struct {
+	services      map[string]*upstream
+	static        map[string][]string
+	adminPorts    map[string]int
+	strategy      Strategy
+	checkInterval time.Duration
+	checkTimeout  time.Duration
+	client        *http.Client
+	log           *slog.Logger
+}
+
+// Option configures a Registry
+type Option func(*Registry)
+
+// WithStrategy sets how calls are balanced between the endpoints of a
+// service, RoundRobin by default
+func WithStrategy(strategy Strategy) Option {
+	return func(r *Registry) {
+		r.strategy = strategy
+	}
+}
+
+// WithEndpoints sets the endpoints of a service, overriding every other
+// source
+func WithEndpoints(name string, urls ...string) Option {
+	return func(r *Registry) {
+		r.static[name] = urls
+	}
+}
+
+// WithAdminPort sets the port the named service serves /_ready on when it
+// has a separate admin listener, overriding every other source
+func WithAdminPort(name string, port int) Option {
+	return func(r *Registry) {
+		r.adminPorts[name] = port
+	}
+}
+
+// WithHealthClient sets the client readiness checks are made with, e.g. one
+// presenting a client certificate to services requiring mutual TLS
+func WithHealthClient(client *http.Client) Option {
+	return func(r *Registry) {
+		r.client = client
+	}
+}
+
+// WithHealthCheck sets how often Watch checks the endpoints' /_ready and how
+// long each check may take
+func WithHealthCheck(interval, timeout time.Duration) Option {
+	return func(r *Registry) {
+		r.checkInterval, r.checkTimeout = interval, timeout
+	}
+}
+
+// WithLogger sets the logger endpoint health changes are logged with, instead
+// of the default logger
+func WithLogger(l *slog.Logger) Option {
+	return func(r *Registry) {
+		r.log = l
+	}
+}
+
+// Load resolves the endpoints of the named services. Each service's
+// endpoints come from, in increasing priority:
+//
+//  1. Defaults
+//  2. The registry file named by APP_SERVICE_REGISTRY_FILE, which maps names
+//     to a URL or a list of URLs
+//  3. APP_SERVICE_<NAME>_URL in cfg's config files or the environment, e.g.
+//     APP_SERVICE_BILLING_URL, holding a comma separated list of URLs
+//  4. WithEndpoints
+//
+// Readiness is checked at /_ready on each endpoint, or on its host at the
+// port set by APP_SERVICE_<NAME>_ADMIN_PORT or WithAdminPort for services
+// with an admin listener, or at the URLs listed, one per endpoint, by
+// APP_SERVICE_<NAME>_HEALTH_URL. The registry file can set them too:
+//
+//	billing:
+//	  urls: [http://billing-1:8001, http://billing-2:8001]
+//	  admin_port: 9001
+//
+// Every service without endpoints and every invalid URL is reported in the
+// returned error.
+func Load(cfg *config.Config, names []string, opts ...Option) (*Registry, error) {
+	r := &Registry{
+		services:      make(map[string]*upstream, len(names)),
+		static:        make(map[string][]string),
+		adminPorts:    make(map[string]int),
+		strategy:      RoundRobin,
+		checkInterval: defaultCheckInterval,
+		checkTimeout:  defaultCheckTimeout,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.client == nil {
+		r.client = &http.Client{}
+	}
+
+	var registry map[string]string
+	if cfg.ServiceRegistryFile != "" {
+		var err error
+		if registry, err = config.ReadFile(cfg.ServiceRegistryFile); err != nil {
+			return nil, fmt.Errorf("reading service registry: %w", err)
+		}
+	}
+	// lookup returns the value of a service setting from the registry file or
+	// cfg, and where it came from
+	lookup := func(fileKeys []string, env string) (value, from string, found bool) {
+		for _, key := range fileKeys {
+			if v, ok := registry[key]; ok {
+				value, from, found = v, "service registry "+cfg.ServiceRegistryFile, true
+			}
+		}
+		if src, ok := cfg.Lookup(env); ok {
+			value, from, found = src.Value, src.String(), true
+		}
+		return value, from, found
+	}
+
+	var errs []error
+	for _, name := range names {
+		key := strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+		env := "APP_SERVICE_" + key
+		urls, from := splitURLs(Defaults[name]), "defaults"
+		if value, f, ok := lookup([]string{key, key + "_URLS"}, env+"_URL"); ok {
+			urls, from = splitURLs(value), f
+		}
+		if static, ok := r.static[name]; ok {
+			urls, from = static, "options"
+		}
+
+		if len(urls) == 0 {
+			errs = append(errs, fmt.Errorf("no endpoints for service %s, set %s_URL", name, env))
+			continue
+		}
+		svc := &upstream{name: name}
+		for _, raw := range urls {
+			u, err := parseURL(raw)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("invalid endpoint of service %s in %s: %w", name, from, err))
+				continue
+			}
+			e := &Endpoint{URL: u}
+			e.healthy.Store(true)
+			svc.endpoints = append(svc.endpoints, e)
+		}
+		if len(svc.endpoints) < len(urls) {
+			continue
+		}
+
+		health, healthFrom, _ := lookup([]string{key + "_HEALTH_URLS", key + "_HEALTH_URL"}, env+"_HEALTH_URL")
+		port, portFrom, _ := lookup([]string{key + "_ADMIN_PORT"}, env+"_ADMIN_PORT")
+		if p, ok := r.adminPorts[name]; ok {
+			port, portFrom = strconv.Itoa(p), "options"
+		}
+		if err := setHealthURLs(svc.endpoints, splitURLs(health), port); err != nil {
+			if health == "" {
+				healthFrom = portFrom
+			}
+			errs = append(errs, fmt.Errorf("invalid health check of service %s in %s: %w", name, healthFrom, err))
+			continue
+		}
+		r.services[name] = svc
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return r, nil
+}
+
+// setHealthURLs sets where the readiness of each endpoint is checked: the
+// listed URLs, one per endpoint, or /_ready on the endpoint's host, on the
+// admin port if there is one
+func setHealthURLs(endpoints []*Endpoint, urls []string, adminPort string) error {
+	if len(urls) > 0 && len(urls) != len(endpoints) {
+		return fmt.Errorf("%d health URLs for %d endpoints", len(urls), len(endpoints))
+	}
+	if adminPort != "" && len(urls) == 0 {
+		if p, err := strconv.Atoi(adminPort); err != nil || p < 1 || p > 65535 {
+			return fmt.Errorf("invalid admin port %q", adminPort)
+		}
+	}
+
+	for i, e := range endpoints {
+		if len(urls) > 0 {
+			u, err := parseURL(urls[i])
+			if err != nil {
+				return err
+			}
+			e.HealthURL = u
+			continue
+		}
+
+		u := *e.URL
+		u.RawPath = ""
+		if adminPort != "" {
+			// The admin listener serves /_ready at its root
+			u.Host = net.JoinHostPort(u.Hostname(), adminPort)
+			u.Path = "/_ready"
+		} else {
+			u.Path += "/_ready"
+		}
+		e.HealthURL = &u
+	}
+	return nil
+}
+
+// Endpoints returns the endpoints of the named service
+func (r *Registry) Endpoints(name string) []*Endpoint {
+	if svc, ok := r.services[name]; ok {
+		return slices.Clone(svc.endpoints)
+	}
+	return nil
+}
+
+// Pick chooses the endpoint of the named service for a call. Endpoints which
+// failed their last readiness check are skipped, unless they all did, as
+// sending calls to them is better than failing every call.
+func (r *Registry) Pick(name string) (*Endpoint, error) {
+	svc, ok := r.services[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownService, name)
+	}
+
+	candidates := make([]*Endpoint, 0, len(svc.endpoints))
+	for _, e := range svc.endpoints {
+		if e.Healthy() {
+			candidates = append(candidates, e)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = svc.endpoints
+	}
+
+	// Starting from the next endpoint in turn spreads ties between the least
+	// loaded endpoints
+	start := int((svc.next.Add(1) - 1) % uint64(len(candidates)))
+	picked := candidates[start]
+	if r.strategy == LeastOutstanding {
+		for i := 1; i < len(candidates); i++ {
+			e := candidates[(start+i)%len(candidates)]
+			if e.Outstanding() < picked.Outstanding() {
+				picked = e
+			}
+		}
+	}
+	return picked, nil
+}
+
+func (r *Registry) logger() *slog.Logger {
+	if r.log != nil {
+		return r.log
+	}
+	return slog.Default()
+}
+
+func splitURLs(list string) []string {
+	var urls []string
+	for u := range strings.SplitSeq(list, ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+func parseURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%q must be an http or https URL with a host", raw)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	return u, nil
+}
@@ -0,0 +1,353 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/z0mbix/go-microservices-monorepo/pkg/config"
+	"github.com/z0mbix/go-microservices-monorepo/pkg/metrics"
+	"github.com/z0mbix/go-microservices-monorepo/pkg/service"
+)
+
+func newConfig(t *testing.T) *config.Config {
+	t.Helper()
+	cfg, err := config.NewWithArgs(nil, config.WithDefaultPort(8002))
+	if err != nil {
+		t.Fatalf("failed to create config: %v", err)
+	}
+	return cfg
+}
+
+func urls(endpoints []*Endpoint) string {
+	var s []string
+	for _, e := range endpoints {
+		s = append(s, e.URL.String())
+	}
+	return strings.Join(s, ",")
+}
+
+func TestLoad(t *testing.T) {
+	envVars := []string{"APP_CONFIG_FILE", "APP_SERVICE_REGISTRY_FILE", "APP_SERVICE_BILLING_URL", "APP_SERVICE_SHIPPING_URL", "APP_SERVICE_USER_URL", "APP_SERVICE_RISK_ENGINE_URL",
+		"APP_SERVICE_BILLING_HEALTH_URL", "APP_SERVICE_SHIPPING_HEALTH_URL", "APP_SERVICE_USER_ADMIN_PORT"}
+	original := make(map[string]string)
+	for _, name := range envVars {
+		original[name] = os.Getenv(name)
+	}
+	defer func() {
+		for name, value := range original {
+			os.Setenv(name, value)
+		}
+	}()
+	clearEnv := func() {
+		for _, name := range envVars {
+			os.Unsetenv(name)
+		}
+	}
+
+	dir := t.TempDir()
+	registryFile := filepath.Join(dir, "services.yaml")
+	registry := "billing:\n  - http://billing-1:8001\n  - http://billing-2:8001/\nshipping: http://shipping:8003\n"
+	if err := os.WriteFile(registryFile, []byte(registry), 0o644); err != nil {
+		t.Fatalf("failed to write registry: %v", err)
+	}
+
+	t.Run("layers", func(t *testing.T) {
+		clearEnv()
+		os.Setenv("APP_SERVICE_REGISTRY_FILE", registryFile)
+		os.Setenv("APP_SERVICE_SHIPPING_URL", "http://shipping-a:8003, http://shipping-b:8003")
+		os.Setenv("APP_SERVICE_RISK_ENGINE_URL", "https://risk.internal/api")
+
+		r, err := Load(newConfig(t), []string{"billing", "shipping", "user", "order", "risk-engine"},
+			WithEndpoints("order", "http://order-static:8002"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		expected := map[string]string{
+			"billing":     "http://billing-1:8001,http://billing-2:8001",
+			"shipping":    "http://shipping-a:8003,http://shipping-b:8003",
+			"user":        "http://localhost:8004",
+			"order":       "http://order-static:8002",
+			"risk-engine": "https://risk.internal/api",
+		}
+		for name, want := range expected {
+			if got := urls(r.Endpoints(name)); got != want {
+				t.Errorf("expected %s endpoints %s, got %s", name, want, got)
+			}
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		clearEnv()
+		os.Setenv("APP_SERVICE_BILLING_URL", "billing:8001")
+		os.Setenv("APP_SERVICE_USER_URL", "ftp://user")
+
+		_, err := Load(newConfig(t), []string{"billing", "user", "risk"})
+		if err == nil {
+			t.Fatal("expected error but got nil")
+		}
+		for _, want := range []string{
+			"invalid endpoint of service billing in APP_SERVICE_BILLING_URL=billing:8001 (env)",
+			`"ftp://user" must be an http or https URL with a host`,
+			"no endpoints for service risk, set APP_SERVICE_RISK_URL",
+		} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("expected error to contain %q, got:\n%v", want, err)
+			}
+		}
+	})
+
+	t.Run("health checks", func(t *testing.T) {
+		clearEnv()
+		healthFile := filepath.Join(dir, "health.yaml")
+		registry := "billing:\n  urls: [http://billing-1:8001/api, http://billing-2:8001]\n  admin_port: 9001\n"
+		if err := os.WriteFile(healthFile, []byte(registry), 0o644); err != nil {
+			t.Fatalf("failed to write registry: %v", err)
+		}
+		os.Setenv("APP_SERVICE_REGISTRY_FILE", healthFile)
+		os.Setenv("APP_SERVICE_SHIPPING_HEALTH_URL", "http://shipping:9003/ready")
+
+		r, err := Load(newConfig(t), []string{"billing", "shipping", "user"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := map[string]string{
+			"billing":  "http://billing-1:9001/_ready,http://billing-2:9001/_ready",
+			"shipping": "http://shipping:9003/ready",
+			"user":     "http://localhost:8004/_ready",
+		}
+		for name, want := range expected {
+			var got []string
+			for _, e := range r.Endpoints(name) {
+				got = append(got, e.HealthURL.String())
+			}
+			if strings.Join(got, ",") != want {
+				t.Errorf("expected %s health URLs %s, got %s", name, want, strings.Join(got, ","))
+			}
+		}
+
+		os.Setenv("APP_SERVICE_BILLING_HEALTH_URL", "http://billing-1:9001/_ready")
+		os.Setenv("APP_SERVICE_USER_ADMIN_PORT", "http")
+		_, err = Load(newConfig(t), []string{"billing", "user"})
+		for _, want := range []string{
+			"invalid health check of service billing in APP_SERVICE_BILLING_HEALTH_URL=http://billing-1:9001/_ready (env): 1 health URLs for 2 endpoints",
+			`invalid health check of service user in APP_SERVICE_USER_ADMIN_PORT=http (env): invalid admin port "http"`,
+		} {
+			if err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("expected error to contain %q, got:\n%v", want, err)
+			}
+		}
+	})
+
+	t.Run("missing registry file", func(t *testing.T) {
+		clearEnv()
+		os.Setenv("APP_SERVICE_REGISTRY_FILE", filepath.Join(dir, "missing.yaml"))
+		if _, err := Load(newConfig(t), []string{"billing"}); err == nil || !strings.Contains(err.Error(), "service registry") {
+			t.Errorf("expected error reading the registry, got %v", err)
+		}
+	})
+}
+
+func TestPick(t *testing.T) {
+	cfg := newConfig(t)
+	endpoints := []string{"http://billing-1", "http://billing-2", "http://billing-3"}
+
+	t.Run("round robin", func(t *testing.T) {
+		r, err := Load(cfg, []string{"billing"}, WithEndpoints("billing", endpoints...))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		r.Endpoints("billing")[1].healthy.Store(false)
+
+		var picked []string
+		for range 4 {
+			e, _ := r.Pick("billing")
+			picked = append(picked, e.URL.Host)
+		}
+		if got := strings.Join(picked, ","); got != "billing-1,billing-3,billing-1,billing-3" {
+			t.Errorf("expected healthy endpoints in turn, got %s", got)
+		}
+
+		for _, e := range r.Endpoints("billing") {
+			e.healthy.Store(false)
+		}
+		if e, err := r.Pick("billing"); err != nil || e == nil {
+			t.Errorf("expected an endpoint when none are healthy, got %v", err)
+		}
+
+		if _, err := r.Pick("ledger"); !errors.Is(err, ErrUnknownService) {
+			t.Errorf("expected ErrUnknownService, got %v", err)
+		}
+	})
+
+	t.Run("least outstanding", func(t *testing.T) {
+		r, err := Load(cfg, []string{"billing"}, WithEndpoints("billing", endpoints...), WithStrategy(LeastOutstanding))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		all := r.Endpoints("billing")
+		all[0].outstanding.Store(3)
+		all[1].outstanding.Store(1)
+		all[2].outstanding.Store(2)
+
+		for range 3 {
+			if e, _ := r.Pick("billing"); e != all[1] {
+				t.Errorf("expected the least loaded endpoint, got %s", e.URL)
+			}
+		}
+	})
+}
+
+func TestTransport(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/slow" {
+			<-release
+		}
+		io.WriteString(w, r.Host+" "+r.URL.RequestURI())
+	}))
+	defer srv.Close()
+
+	r, err := Load(newConfig(t), []string{"billing"}, WithEndpoints("billing", srv.URL+"/api"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client := &http.Client{Transport: r.Transport(nil)}
+
+	resp, err := client.Get("http://billing/invoices?id=1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if want := strings.TrimPrefix(srv.URL, "http://") + " /api/invoices?id=1"; string(body) != want {
+		t.Errorf("expected the request to be sent to the endpoint as %q, got %q", want, body)
+	}
+
+	e := r.Endpoints("billing")[0]
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		resp, err := client.Get("http://billing/slow")
+		if err == nil {
+			io.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+	}()
+	for e.Outstanding() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	<-done
+	if e.Outstanding() != 0 {
+		t.Errorf("expected no outstanding calls once the body is closed, got %d", e.Outstanding())
+	}
+
+	if _, err := client.Get(srv.URL + "/api/direct"); err != nil {
+		t.Errorf("expected other hosts to be called directly, got %v", err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	var ready atomic.Bool
+	ready.Store(true)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_ready" || !ready.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	r, err := Load(newConfig(t), []string{"billing"},
+		WithEndpoints("billing", srv.URL),
+		WithHealthCheck(10*time.Millisecond, time.Second),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r.Watch(ctx)
+
+	e := r.Endpoints("billing")[0]
+	waitFor := func(healthy bool) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for e.Healthy() != healthy {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected the endpoint's health to become %v", healthy)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	ready.Store(false)
+	waitFor(false)
+	if err := r.CheckHealth(ctx); err == nil || !strings.Contains(err.Error(), "billing") {
+		t.Errorf("expected the readiness check to name billing, got %v", err)
+	}
+
+	ready.Store(true)
+	waitFor(true)
+	if err := r.CheckHealth(ctx); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestWatchAdminPort(t *testing.T) {
+	svc, err := service.NewWithName("billing", service.WithAdminPort(9001), service.WithMetricsRegistry(metrics.NewRegistry()))
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	srv := httptest.NewServer(svc.Handler())
+	defer srv.Close()
+	admin := httptest.NewServer(svc.AdminHandler())
+	defer admin.Close()
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(admin.URL, "http://"))
+	adminPort, _ := strconv.Atoi(port)
+
+	check := func(opts ...Option) *Endpoint {
+		t.Helper()
+		opts = append(opts, WithEndpoints("billing", srv.URL))
+		r, err := Load(newConfig(t), []string{"billing"}, opts...)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		r.checkAll(context.Background())
+		return r.Endpoints("billing")[0]
+	}
+
+	// The service only serves /_ready on its admin port
+	if e := check(); e.Healthy() {
+		t.Error("expected the endpoint to fail its check on the main port")
+	}
+	if e := check(WithAdminPort("billing", adminPort)); !e.Healthy() {
+		t.Errorf("expected the endpoint to pass its check at %s", e.HealthURL)
+	}
+
+	var calls atomic.Int32
+	client := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		calls.Add(1)
+		return http.DefaultTransport.RoundTrip(req)
+	})}
+	if e := check(WithAdminPort("billing", adminPort), WithHealthClient(client)); !e.Healthy() || calls.Load() != 1 {
+		t.Errorf("expected the check to be made with the health client, made %d calls", calls.Load())
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,96 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Watch checks the readiness of every endpoint at its HealthURL until ctx is
+// cancelled, ejecting those which fail from Pick until they pass again
+func (r *Registry) Watch(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(r.checkInterval)
+		defer ticker.Stop()
+		for {
+			r.checkAll(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// checkAll checks every endpoint concurrently
+func (r *Registry) checkAll(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, svc := range r.services {
+		for _, e := range svc.endpoints {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				r.check(ctx, svc.name, e)
+			}()
+		}
+	}
+	wg.Wait()
+}
+
+// check updates the health of an endpoint from its readiness check
+func (r *Registry) check(ctx context.Context, name string, e *Endpoint) {
+	err := r.ready(ctx, e)
+	if ctx.Err() != nil {
+		return
+	}
+
+	healthy := err == nil
+	if e.healthy.Swap(healthy) == healthy {
+		return
+	}
+	if healthy {
+		r.logger().InfoContext(ctx, "restored service endpoint", "service", name, "endpoint", e.URL.Redacted())
+	} else {
+		r.logger().WarnContext(ctx, "ejected unhealthy service endpoint", "service", name, "endpoint", e.URL.Redacted(), "error", err)
+	}
+}
+
+func (r *Registry) ready(ctx context.Context, e *Endpoint) error {
+	ctx, cancel := context.WithTimeout(ctx, r.checkTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.HealthURL.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("readiness check responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// CheckHealth reports an error naming the services without a healthy
+// endpoint, so the registry can be used as a readiness check
+func (r *Registry) CheckHealth(ctx context.Context) error {
+	var unhealthy []string
+	for name, svc := range r.services {
+		if !slices.ContainsFunc(svc.endpoints, (*Endpoint).Healthy) {
+			unhealthy = append(unhealthy, name)
+		}
+	}
+	if len(unhealthy) > 0 {
+		slices.Sort(unhealthy)
+		return errors.New("no healthy endpoints for " + strings.Join(unhealthy, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package discovery
+
+import (
+	"io"
+	"net/http"
+	"sync"
+)
+
+// Transport returns an http.RoundTripper which sends requests for a service
+// by its name, e.g. http://billing/invoices, to one of its endpoints, using
+// base, or http.DefaultTransport if base is nil. Requests for other hosts
+// are sent as they are. client.WithRegistry uses it with a circuit breaker
+// per endpoint.
+func (r *Registry) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{registry: r, base: base}
+}
+
+type transport struct {
+	registry *Registry
+	base     http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if _, ok := t.registry.services[req.URL.Host]; !ok {
+		return t.base.RoundTrip(req)
+	}
+	e, err := t.registry.Pick(req.URL.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	// RoundTrippers must not modify the caller's request
+	req = req.Clone(req.Context())
+	req.URL.Scheme, req.URL.Host = e.URL.Scheme, e.URL.Host
+	req.URL.Path = e.URL.Path + req.URL.Path
+	if req.URL.RawPath != "" {
+		req.URL.RawPath = e.URL.EscapedPath() + req.URL.RawPath
+	}
+	req.Host = ""
+
+	e.outstanding.Add(1)
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		e.outstanding.Add(-1)
+		return nil, err
+	}
+	// The call is in progress until its body has been read
+	resp.Body = &trackedBody{ReadCloser: resp.Body, done: func() { e.outstanding.Add(-1) }}
+	return resp, nil
+}
+
+// trackedBody calls done once, when the body is closed
+type trackedBody struct {
+	io.ReadCloser
+	once sync.Once
+	done func()
+}
+
+func (b *trackedBody) Close() error {
+	b.once.Do(b.done)
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,50 @@
+package featureflag
+
+import "context"
+
+type contextKey int
+
+const (
+	setKey contextKey = iota
+	attrsKey
+)
+
+// NewContext returns a copy of ctx carrying set, for Enabled to evaluate
+func NewContext(ctx context.Context, set *Set) context.Context {
+	return context.WithValue(ctx, setKey, set)
+}
+
+// FromContext returns the Set stored in ctx by NewContext, or nil
+func FromContext(ctx context.Context) *Set {
+	set, _ := ctx.Value(setKey).(*Set)
+	return set
+}
+
+// WithAttributes returns a copy of ctx with attrs added to those flags are
+// evaluated for, e.g. the user ID set by authentication middleware. Empty
+// fields keep the values already in ctx.
+func WithAttributes(ctx context.Context, attrs Attributes) context.Context {
+	merged := AttributesFromContext(ctx)
+	if attrs.UserID != "" {
+		merged.UserID = attrs.UserID
+	}
+	if attrs.Environment != "" {
+		merged.Environment = attrs.Environment
+	}
+	if attrs.Version != "" {
+		merged.Version = attrs.Version
+	}
+	return context.WithValue(ctx, attrsKey, merged)
+}
+
+// AttributesFromContext returns the attributes added to ctx by WithAttributes
+func AttributesFromContext(ctx context.Context) Attributes {
+	attrs, _ := ctx.Value(attrsKey).(Attributes)
+	return attrs
+}
+
+// Enabled reports whether the named flag of the Set in ctx is on for the
+// attributes in ctx
+func Enabled(ctx context.Context, name string) bool {
+	return FromContext(ctx).Enabled(name, AttributesFromContext(ctx))
+}
@@ -0,0 +1,259 @@
+package featureflag
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"path"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/z0mbix/go-microservices-monorepo/pkg/config"
+)
+
+// Flag states reported by Flag.State
+const (
+	StateOff = "off"
+	StateOn  = "on"
+	// StatePartial is a flag which is on for some users only
+	StatePartial = "partial"
+)
+
+// Attributes describe who a flag is being evaluated for
+type Attributes struct {
+	UserID      string
+	Environment string
+	Version     string
+}
+
+// Flag is a feature flag. A flag which is not Enabled is off for everyone.
+// Otherwise it is on when the environment and version match the targeting
+// lists that are set, and the user is either listed in Users or falls in the
+// Rollout percentage. A flag without a Rollout is on for every user.
+type Flag struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	// Rollout is the percentage of users, 0 to 100, the flag is on for.
+	// Users are assigned by a hash of the flag name and their ID, so each
+	// user consistently gets the same result as the rollout grows.
+	Rollout *float64 `json:"rollout,omitempty"`
+	// Users the flag is always on for, whatever the rollout
+	Users        []string `json:"users,omitempty"`
+	Environments []string `json:"environments,omitempty"`
+	// Versions are patterns matched with path.Match, e.g. 1.4.*
+	Versions []string `json:"versions,omitempty"`
+}
+
+// Evaluate reports whether the flag is on for attrs
+func (f Flag) Evaluate(attrs Attributes) bool {
+	if !f.targets(attrs) {
+		return false
+	}
+	if attrs.UserID != "" && slices.Contains(f.Users, attrs.UserID) {
+		return true
+	}
+	if f.Rollout == nil || *f.Rollout >= 100 {
+		return true
+	}
+	if attrs.UserID == "" {
+		return false
+	}
+	return bucket(f.Name, attrs.UserID) < *f.Rollout
+}
+
+// State reports whether the flag is on, off or on for some users only, for
+// the environment and version in attrs
+func (f Flag) State(attrs Attributes) string {
+	switch {
+	case !f.targets(attrs):
+		return StateOff
+	case f.Rollout == nil || *f.Rollout >= 100:
+		return StateOn
+	case *f.Rollout <= 0 && len(f.Users) == 0:
+		return StateOff
+	default:
+		return StatePartial
+	}
+}
+
+// targets reports whether the flag is enabled for the environment and
+// version in attrs
+func (f Flag) targets(attrs Attributes) bool {
+	if !f.Enabled {
+		return false
+	}
+	if len(f.Environments) > 0 && !slices.Contains(f.Environments, attrs.Environment) {
+		return false
+	}
+	if len(f.Versions) > 0 && !slices.ContainsFunc(f.Versions, func(pattern string) bool {
+		matched, _ := path.Match(pattern, attrs.Version)
+		return matched
+	}) {
+		return false
+	}
+	return true
+}
+
+// bucket places a user in the range [0, 100) for a flag
+func bucket(flag, userID string) float64 {
+	h := fnv.New32a()
+	h.Write([]byte(flag + ":" + userID))
+	return float64(h.Sum32()%10000) / 100
+}
+
+// Set is an immutable set of flags. A nil Set has no flags.
+type Set struct {
+	flags map[string]Flag
+}
+
+// New returns a Set of flags
+func New(flags ...Flag) *Set {
+	s := &Set{flags: make(map[string]Flag, len(flags))}
+	for _, f := range flags {
+		f.Name = normalize(f.Name)
+		s.flags[f.Name] = f
+	}
+	return s
+}
+
+// Enabled reports whether the named flag is on for attrs. Unknown flags are
+// off.
+func (s *Set) Enabled(name string, attrs Attributes) bool {
+	if s == nil {
+		return false
+	}
+	f, ok := s.flags[normalize(name)]
+	return ok && f.Evaluate(attrs)
+}
+
+// Flag returns the named flag
+func (s *Set) Flag(name string) (Flag, bool) {
+	if s == nil {
+		return Flag{}, false
+	}
+	f, ok := s.flags[normalize(name)]
+	return f, ok
+}
+
+// Flags returns every flag, sorted by name
+func (s *Set) Flags() []Flag {
+	if s == nil {
+		return nil
+	}
+	flags := make([]Flag, 0, len(s.flags))
+	for _, f := range s.flags {
+		flags = append(flags, f)
+	}
+	slices.SortFunc(flags, func(a, b Flag) int { return strings.Compare(a.Name, b.Name) })
+	return flags
+}
+
+// With returns a Set with the flags of s and flags, which replace those of s
+// with the same name
+func (s *Set) With(flags ...Flag) *Set {
+	return New(append(s.Flags(), flags...)...)
+}
+
+// Equal reports whether s and other have the same flags
+func (s *Set) Equal(other *Set) bool {
+	return slices.EqualFunc(s.Flags(), other.Flags(), func(a, b Flag) bool {
+		return reflect.DeepEqual(a, b)
+	})
+}
+
+// normalize makes flag names match however they were written, e.g. in
+// APP_FEATURES or a file
+func normalize(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "-", "_"))
+}
+
+// Load reads flags from a YAML, JSON or TOML file. A flag is either a
+// boolean or a table of its fields:
+//
+//	new_invoices: true
+//	checkout_v2:
+//	  enabled: true
+//	  rollout: 25
+//	  users: [u-1001, u-1002]
+//	  environments: [staging, production]
+//	  versions: ["1.4.*"]
+//
+// A table without enabled is enabled. An empty file name returns an empty
+// Set. Every invalid flag is reported in the returned error.
+func Load(file string) (*Set, error) {
+	if file == "" {
+		return New(), nil
+	}
+	sections, err := config.ReadFileSections(file)
+	if err != nil {
+		return nil, err
+	}
+
+	set := New()
+	var errs []error
+	for name, fields := range sections {
+		name = normalize(name)
+		f := Flag{Name: name, Enabled: true}
+		if _, ok := set.flags[name]; ok {
+			errs = append(errs, fmt.Errorf("flag %s is defined more than once in %s", name, file))
+			continue
+		}
+		if _, ok := fields[""]; ok && len(fields) > 1 {
+			errs = append(errs, fmt.Errorf("flag %s in %s is both a boolean and a table", name, file))
+			continue
+		}
+
+		for key, value := range fields {
+			field := strings.ToLower(key)
+			var err error
+			switch field {
+			case "", "enabled":
+				f.Enabled, err = strconv.ParseBool(value)
+			case "rollout":
+				var rollout float64
+				rollout, err = strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+				if err == nil && (rollout < 0 || rollout > 100) {
+					err = errors.New("must be between 0 and 100")
+				}
+				f.Rollout = &rollout
+			case "users":
+				f.Users = split(value)
+			case "environments":
+				f.Environments = split(value)
+			case "versions":
+				f.Versions = split(value)
+				for _, pattern := range f.Versions {
+					if _, matchErr := path.Match(pattern, ""); matchErr != nil {
+						err = fmt.Errorf("invalid pattern %q", pattern)
+					}
+				}
+			default:
+				err = errors.New("unknown field")
+			}
+			if err != nil {
+				if field == "" {
+					field = "value"
+				}
+				errs = append(errs, fmt.Errorf("invalid %s of flag %s in %s: %w", field, name, file, err))
+			}
+		}
+		set.flags[name] = f
+	}
+	if len(errs) > 0 {
+		slices.SortFunc(errs, func(a, b error) int { return strings.Compare(a.Error(), b.Error()) })
+		return nil, errors.Join(errs...)
+	}
+	return set, nil
+}
+
+func split(list string) []string {
+	var items []string
+	for item := range strings.SplitSeq(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,217 @@
+package featureflag
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func rollout(percent float64) *float64 {
+	return &percent
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name  string
+		flag  Flag
+		attrs Attributes
+		want  bool
+		state string
+	}{
+		{"disabled", Flag{Name: "f"}, Attributes{UserID: "u-1"}, false, StateOff},
+		{"enabled", Flag{Name: "f", Enabled: true}, Attributes{}, true, StateOn},
+		{"environment", Flag{Name: "f", Enabled: true, Environments: []string{"staging"}}, Attributes{Environment: "staging"}, true, StateOn},
+		{"other environment", Flag{Name: "f", Enabled: true, Environments: []string{"staging"}}, Attributes{Environment: "production"}, false, StateOff},
+		{"version", Flag{Name: "f", Enabled: true, Versions: []string{"1.4.*"}}, Attributes{Version: "1.4.2"}, true, StateOn},
+		{"other version", Flag{Name: "f", Enabled: true, Versions: []string{"1.4.*"}}, Attributes{Version: "1.5.0"}, false, StateOff},
+		{"no rollout", Flag{Name: "f", Enabled: true, Rollout: rollout(0)}, Attributes{UserID: "u-1"}, false, StateOff},
+		{"full rollout", Flag{Name: "f", Enabled: true, Rollout: rollout(100)}, Attributes{UserID: "u-1"}, true, StateOn},
+		{"rollout without a user", Flag{Name: "f", Enabled: true, Rollout: rollout(50)}, Attributes{}, false, StatePartial},
+		{"listed user", Flag{Name: "f", Enabled: true, Rollout: rollout(0), Users: []string{"u-1"}}, Attributes{UserID: "u-1"}, true, StatePartial},
+		{"listed user in another environment", Flag{Name: "f", Enabled: true, Users: []string{"u-1"}, Environments: []string{"staging"}}, Attributes{UserID: "u-1"}, false, StateOff},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.flag.Evaluate(tt.attrs); got != tt.want {
+				t.Errorf("expected Evaluate to return %v, got %v", tt.want, got)
+			}
+			if got := tt.flag.State(tt.attrs); got != tt.state {
+				t.Errorf("expected state %q, got %q", tt.state, got)
+			}
+		})
+	}
+}
+
+func TestRollout(t *testing.T) {
+	flag := Flag{Name: "checkout_v2", Enabled: true, Rollout: rollout(25)}
+	wider := Flag{Name: "checkout_v2", Enabled: true, Rollout: rollout(50)}
+
+	on := 0
+	for i := range 10000 {
+		attrs := Attributes{UserID: fmt.Sprintf("u-%d", i)}
+		if flag.Evaluate(attrs) != flag.Evaluate(attrs) {
+			t.Fatalf("expected a consistent result for %s", attrs.UserID)
+		}
+		if flag.Evaluate(attrs) {
+			on++
+			if !wider.Evaluate(attrs) {
+				t.Errorf("expected %s to stay in the rollout as it grows", attrs.UserID)
+			}
+		}
+	}
+	if on < 2300 || on > 2700 {
+		t.Errorf("expected about 25%% of users in the rollout, got %d of 10000", on)
+	}
+}
+
+func TestSet(t *testing.T) {
+	set := New(Flag{Name: "Checkout-V2", Enabled: true}, Flag{Name: "refunds"})
+	if !set.Enabled("checkout_v2", Attributes{}) || !set.Enabled("CHECKOUT-V2", Attributes{}) {
+		t.Error("expected flag names to match however they are written")
+	}
+	if set.Enabled("refunds", Attributes{}) || set.Enabled("unknown", Attributes{}) {
+		t.Error("expected disabled and unknown flags to be off")
+	}
+
+	next := set.With(Flag{Name: "refunds", Enabled: true})
+	if !next.Enabled("refunds", Attributes{}) || set.Enabled("refunds", Attributes{}) {
+		t.Error("expected With to return a new set with the flag replaced")
+	}
+	if set.Equal(next) || !set.Equal(New(Flag{Name: "refunds"}, Flag{Name: "checkout_v2", Enabled: true})) {
+		t.Error("expected sets to be equal only with the same flags")
+	}
+
+	var empty *Set
+	if empty.Enabled("refunds", Attributes{}) || len(empty.Flags()) != 0 || !empty.Equal(New()) {
+		t.Error("expected a nil set to have no flags")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		return path
+	}
+
+	path := write("flags.yaml", `
+new_invoices: true
+refunds: false
+checkout_v2:
+  rollout: 25%
+  users: [u-1001, u-1002]
+  environments: [staging, production]
+  versions: ["1.4.*"]
+`)
+	set, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	flags := set.Flags()
+	if len(flags) != 3 {
+		t.Fatalf("expected 3 flags, got %+v", flags)
+	}
+	checkout, _ := set.Flag("checkout_v2")
+	if !checkout.Enabled || checkout.Rollout == nil || *checkout.Rollout != 25 ||
+		strings.Join(checkout.Users, ",") != "u-1001,u-1002" ||
+		strings.Join(checkout.Environments, ",") != "staging,production" ||
+		strings.Join(checkout.Versions, ",") != "1.4.*" {
+		t.Errorf("unexpected checkout_v2 flag: %+v", checkout)
+	}
+	if !set.Enabled("new_invoices", Attributes{}) || set.Enabled("refunds", Attributes{}) {
+		t.Error("expected boolean flags to be loaded")
+	}
+	if !set.Enabled("checkout_v2", Attributes{UserID: "u-1001", Environment: "production", Version: "1.4.0"}) {
+		t.Error("expected checkout_v2 to be on for a listed user")
+	}
+
+	t.Run("empty file name", func(t *testing.T) {
+		set, err := Load("")
+		if err != nil || len(set.Flags()) != 0 {
+			t.Errorf("expected an empty set, got %v, %v", set.Flags(), err)
+		}
+	})
+
+	t.Run("names ending in a field", func(t *testing.T) {
+		path := write("names.yaml", "premium_users: true\nshow_versions: false\nbeta_enabled:\n  users: [u-1]\n")
+		set, err := Load(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		premium, _ := set.Flag("premium_users")
+		show, _ := set.Flag("show_versions")
+		beta, _ := set.Flag("beta_enabled")
+		if len(set.Flags()) != 3 || !premium.Enabled || premium.Users != nil || show.Enabled || show.Versions != nil ||
+			strings.Join(beta.Users, ",") != "u-1" {
+			t.Errorf("expected the names to be kept whole, got %+v", set.Flags())
+		}
+	})
+
+	t.Run("invalid flags", func(t *testing.T) {
+		path := write("invalid.yaml", `
+refunds: maybe
+checkout_v2:
+  rollout: 150
+  versions: ["1.[4"]
+`)
+		_, err := Load(path)
+		if err == nil {
+			t.Fatal("expected error but got nil")
+		}
+		for _, want := range []string{
+			"invalid value of flag refunds",
+			"invalid rollout of flag checkout_v2",
+			"invalid versions of flag checkout_v2",
+		} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("expected error to contain %q, got:\n%v", want, err)
+			}
+		}
+	})
+
+	t.Run("ambiguous flags", func(t *testing.T) {
+		path := write("ambiguous.toml", "premium = true\nnew-invoices = true\nnew_invoices = false\n[premium]\nusers = [\"u-1\"]\n[checkout]\nrollout.users = 10\n")
+		_, err := Load(path)
+		if err == nil {
+			t.Fatal("expected error but got nil")
+		}
+		for _, want := range []string{
+			"flag premium in " + path + " is both a boolean and a table",
+			"flag new_invoices is defined more than once in " + path,
+			"invalid rollout_users of flag checkout in " + path + ": unknown field",
+		} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("expected error to contain %q, got:\n%v", want, err)
+			}
+		}
+	})
+}
+
+func TestContext(t *testing.T) {
+	set := New(Flag{Name: "beta", Enabled: true, Users: []string{"u-1"}, Rollout: rollout(0), Environments: []string{"staging"}})
+	ctx := context.Background()
+	if Enabled(ctx, "beta") {
+		t.Error("expected flags to be off without a set in the context")
+	}
+
+	ctx = NewContext(ctx, set)
+	ctx = WithAttributes(ctx, Attributes{Environment: "staging", Version: "1.0.0"})
+	if Enabled(ctx, "beta") {
+		t.Error("expected the flag to be off without a user")
+	}
+
+	ctx = WithAttributes(ctx, Attributes{UserID: "u-1"})
+	if attrs := AttributesFromContext(ctx); attrs != (Attributes{UserID: "u-1", Environment: "staging", Version: "1.0.0"}) {
+		t.Errorf("expected attributes to be merged, got %+v", attrs)
+	}
+	if !Enabled(ctx, "beta") {
+		t.Error("expected the flag to be on for the user")
+	}
+}
@@ -0,0 +1,113 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+)
+
+type contextKey int
+
+const (
+	loggerKey contextKey = iota
+	attrsKey
+)
+
+// WithContext returns a copy of ctx carrying l, for FromContext to return
+func WithContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, l)
+}
+
+// FromContext returns the logger stored in ctx by WithContext, or the default
+// logger. Records it logs carry the attributes added by ContextWithAttrs,
+// even when logged without a context, e.g. with Info rather than InfoContext.
+func FromContext(ctx context.Context) *slog.Logger {
+	l, ok := ctx.Value(loggerKey).(*slog.Logger)
+	if !ok {
+		l = slog.Default()
+	}
+
+	next := l.Handler()
+	if ch, ok := next.(*ContextHandler); ok {
+		next = ch.next
+	}
+	return slog.New(&ContextHandler{next: next, ctx: ctx})
+}
+
+// ContextWithAttrs returns a copy of ctx with attrs added to those logged by
+// a ContextHandler, e.g. a request or tenant ID. Attributes with the same key
+// replace earlier ones.
+func ContextWithAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	existing := AttrsFromContext(ctx)
+	merged := make([]slog.Attr, 0, len(existing)+len(attrs))
+	for _, a := range existing {
+		if !slices.ContainsFunc(attrs, func(b slog.Attr) bool { return b.Key == a.Key }) {
+			merged = append(merged, a)
+		}
+	}
+	merged = append(merged, attrs...)
+	return context.WithValue(ctx, attrsKey, merged)
+}
+
+// AttrsFromContext returns the attributes added to ctx by ContextWithAttrs
+func AttrsFromContext(ctx context.Context) []slog.Attr {
+	attrs, _ := ctx.Value(attrsKey).([]slog.Attr)
+	return attrs
+}
+
+// ContextHandler adds the attributes stored in the context by
+// ContextWithAttrs to every record, unless the record already has an
+// attribute with the same key
+type ContextHandler struct {
+	next slog.Handler
+	// ctx is used for records logged without a context, see FromContext
+	ctx context.Context
+}
+
+// NewContextHandler wraps next so records carry the context attributes
+func NewContextHandler(next slog.Handler) *ContextHandler {
+	if ch, ok := next.(*ContextHandler); ok {
+		return ch
+	}
+	return &ContextHandler{next: next}
+}
+
+func (h *ContextHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(h.context(ctx), level)
+}
+
+func (h *ContextHandler) Handle(ctx context.Context, r slog.Record) error {
+	ctx = h.context(ctx)
+
+	if attrs := AttrsFromContext(ctx); len(attrs) > 0 {
+		var keys []string
+		r.Attrs(func(a slog.Attr) bool {
+			keys = append(keys, a.Key)
+			return true
+		})
+		for _, a := range attrs {
+			if !slices.Contains(keys, a.Key) {
+				r.AddAttrs(a)
+			}
+		}
+	}
+
+	return h.next.Handle(ctx, r)
+}
+
+func (h *ContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &ContextHandler{next: h.next.WithAttrs(attrs), ctx: h.ctx}
+}
+
+func (h *ContextHandler) WithGroup(name string) slog.Handler {
+	return &ContextHandler{next: h.next.WithGroup(name), ctx: h.ctx}
+}
+
+// context returns the context bound by FromContext when slog passes the
+// background context for a record logged without one
+func (h *ContextHandler) context(ctx context.Context) context.Context {
+	if h.ctx != nil && (ctx == nil || ctx == context.Background()) {
+		return h.ctx
+	}
+	return ctx
+}
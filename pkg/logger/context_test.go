@@ -0,0 +1,81 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestFromContext(t *testing.T) {
+	if FromContext(context.Background()).Handler() == nil {
+		t.Fatal("expected a logger when the context has none")
+	}
+
+	var buf bytes.Buffer
+	l := slog.New(slog.NewJSONHandler(&buf, nil))
+	ctx := WithContext(context.Background(), l)
+	ctx = ContextWithAttrs(ctx, slog.String("request_id", "abc123"), slog.String("tenant", "acme"))
+
+	FromContext(ctx).Info("without context")
+	FromContext(ctx).InfoContext(ctx, "with context")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got: %s", buf.String())
+	}
+	for _, line := range lines {
+		if !strings.Contains(line, `"request_id":"abc123"`) || !strings.Contains(line, `"tenant":"acme"`) {
+			t.Errorf("expected context attributes in %s", line)
+		}
+	}
+}
+
+func TestContextWithAttrs(t *testing.T) {
+	ctx := ContextWithAttrs(context.Background(), slog.String("tenant", "acme"), slog.String("user_id", "1"))
+	child := ContextWithAttrs(ctx, slog.String("user_id", "2"))
+
+	attrs := AttrsFromContext(child)
+	if len(attrs) != 2 || attrs[0].String() != "tenant=acme" || attrs[1].String() != "user_id=2" {
+		t.Errorf("expected later attributes to replace earlier ones, got %v", attrs)
+	}
+	if parent := AttrsFromContext(ctx); parent[1].String() != "user_id=1" {
+		t.Errorf("expected parent context to be unchanged, got %v", parent)
+	}
+}
+
+func TestContextHandler(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(NewContextHandler(slog.NewJSONHandler(&buf, nil)))
+	ctx := ContextWithAttrs(context.Background(), slog.String("request_id", "from-context"))
+
+	l.InfoContext(ctx, "explicit", "request_id", "from-record")
+	if strings.Count(buf.String(), "request_id") != 1 || !strings.Contains(buf.String(), "from-record") {
+		t.Errorf("expected record attribute to take precedence: %s", buf.String())
+	}
+
+	buf.Reset()
+	l.Info("no context")
+	if strings.Contains(buf.String(), "request_id") {
+		t.Errorf("expected no context attributes without a context: %s", buf.String())
+	}
+
+	if NewContextHandler(l.Handler()) != l.Handler() {
+		t.Error("expected NewContextHandler not to wrap a ContextHandler twice")
+	}
+}
+
+func TestContextAttrsAreRedacted(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New("info", WithWriter(&buf), WithRedaction())
+	if err != nil {
+		t.Fatalf("New returned unexpected error: %v", err)
+	}
+
+	ctx := ContextWithAttrs(context.Background(), slog.String("user", "jane.doe@example.com"))
+	l.InfoContext(ctx, "login")
+	if strings.Contains(buf.String(), "jane.doe@example.com") || !strings.Contains(buf.String(), `"user":"[REDACTED]"`) {
+		t.Errorf("expected context attribute to be redacted: %s", buf.String())
+	}
+}
@@ -1,36 +1,228 @@
 package logger
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"strings"
+	"sync"
 )
 
-// New returns a new slog.Logger instance with the specified log level
-func New(level string) (*slog.Logger, error) {
-	level = strings.ToLower(level)
-	var logLevel slog.Level
+// Supported log formats
+const (
+	FormatJSON   = "json"
+	FormatText   = "text"
+	FormatPretty = "pretty"
+)
+
+// Option configures a logger created by New or NewFromOptions
+type Option func(*options)
+
+type options struct {
+	level      string
+	levelVar   *slog.LevelVar
+	format     string
+	writer     io.Writer
+	handler    slog.Handler
+	redact     []RedactOption
+	redacted   bool
+	sample     []SampleOption
+	sampled    bool
+	setDefault bool
+}
+
+// WithLevel sets the minimum level logged: debug, info (the default), warn or
+// error
+func WithLevel(level string) Option {
+	return func(o *options) {
+		o.level = level
+	}
+}
+
+// WithLevelVar makes the logger read its level from lv, which is set to the
+// level given by WithLevel. Changing lv later changes the level of the running logger.
+func WithLevelVar(lv *slog.LevelVar) Option {
+	return func(o *options) {
+		o.levelVar = lv
+	}
+}
+
+// WithFormat sets the output format: json (the default), text or pretty, a
+// colourised format for reading logs in a terminal
+func WithFormat(format string) Option {
+	return func(o *options) {
+		o.format = format
+	}
+}
+
+// WithWriter sets where logs are written, defaulting to stdout. See
+// OpenOutput for writing to files or syslog.
+func WithWriter(w io.Writer) Option {
+	return func(o *options) {
+		o.writer = w
+	}
+}
 
-	switch level {
+// WithHandler makes the logger write records to h rather than creating a
+// handler from the format and writer. Records below the logger's level are
+// dropped before reaching h.
+func WithHandler(h slog.Handler) Option {
+	return func(o *options) {
+		o.handler = h
+	}
+}
+
+// AsDefault installs the logger as the slog default, so it is used by the
+// slog and log package functions. Nothing else changes global state.
+func AsDefault() Option {
+	return func(o *options) {
+		o.setDefault = true
+	}
+}
+
+// WithRedaction redacts sensitive values before they are written. See
+// NewRedactHandler for the defaults.
+func WithRedaction(opts ...RedactOption) Option {
+	return func(o *options) {
+		o.redact = opts
+		o.redacted = true
+	}
+}
+
+// WithSampling drops repeated debug and info records. See
+// NewSamplingHandler for the defaults.
+func WithSampling(opts ...SampleOption) Option {
+	return func(o *options) {
+		o.sample = opts
+		o.sampled = true
+	}
+}
+
+// ParseLevel converts a level name (debug, info, warn or error) to a slog.Level
+func ParseLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
 	case "debug":
-		logLevel = slog.LevelDebug
+		return slog.LevelDebug, nil
 	case "info":
-		logLevel = slog.LevelInfo
+		return slog.LevelInfo, nil
 	case "warn":
-		logLevel = slog.LevelWarn
+		return slog.LevelWarn, nil
 	case "error":
-		logLevel = slog.LevelError
+		return slog.LevelError, nil
 	default:
-		return nil, fmt.Errorf("invalid log level: %s", level)
+		return 0, fmt.Errorf("invalid log level: %s", strings.ToLower(level))
+	}
+}
+
+// New returns a new slog.Logger with the specified log level. It is
+// NewFromOptions with WithLevel(level) applied first.
+func New(level string, opts ...Option) (*slog.Logger, error) {
+	return NewFromOptions(append([]Option{WithLevel(level)}, opts...)...)
+}
+
+// NewFromOptions returns a new slog.Logger configured only by opts, logging
+// JSON to stdout at info level by default. Unless AsDefault is given, it has
+// no side effects.
+func NewFromOptions(opts ...Option) (*slog.Logger, error) {
+	o := options{
+		level:    "info",
+		levelVar: new(slog.LevelVar),
+		format:   FormatJSON,
+		writer:   os.Stdout,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	logLevel, err := ParseLevel(o.level)
+	if err != nil {
+		return nil, err
+	}
+	o.levelVar.Set(logLevel)
+
+	handlerOptions := &slog.HandlerOptions{Level: o.levelVar}
+
+	var logHandler slog.Handler
+	switch {
+	case o.handler != nil:
+		logHandler = &levelHandler{Handler: o.handler, level: o.levelVar}
+	case strings.ToLower(o.format) == FormatJSON || o.format == "":
+		logHandler = slog.NewJSONHandler(o.writer, handlerOptions)
+	case strings.ToLower(o.format) == FormatText:
+		logHandler = slog.NewTextHandler(o.writer, handlerOptions)
+	case strings.ToLower(o.format) == FormatPretty:
+		logHandler = NewPrettyHandler(o.writer, handlerOptions)
+	default:
+		return nil, fmt.Errorf("invalid log format: %s", o.format)
+	}
+	if lw, ok := o.writer.(levelWriter); ok && o.handler == nil {
+		logHandler = &writeLevelHandler{Handler: logHandler, w: lw}
+	}
+
+	// Context attributes are added before redaction so they are redacted too
+	if o.redacted {
+		logHandler = NewRedactHandler(logHandler, o.redact...)
+	}
+	logHandler = NewContextHandler(logHandler)
+	// Sampling comes first so dropped records are never redacted
+	if o.sampled {
+		logHandler = NewSamplingHandler(logHandler, o.sample...)
 	}
 
-	logHandler := slog.NewJSONHandler(
-		os.Stdout,
-		&slog.HandlerOptions{Level: logLevel},
-	)
 	logger := slog.New(logHandler)
-	slog.SetDefault(logger)
+	if o.setDefault {
+		slog.SetDefault(logger)
+	}
 
 	return logger, nil
 }
+
+// levelWriter is an output which writes each record at a severity, such as
+// syslog. SetLevel is called with the lock held before a record is written.
+type levelWriter interface {
+	io.Writer
+	sync.Locker
+	SetLevel(slog.Level)
+}
+
+// writeLevelHandler tells a levelWriter the level of each record it writes
+type writeLevelHandler struct {
+	slog.Handler
+	w levelWriter
+}
+
+func (h *writeLevelHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.w.Lock()
+	defer h.w.Unlock()
+	h.w.SetLevel(r.Level)
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *writeLevelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &writeLevelHandler{Handler: h.Handler.WithAttrs(attrs), w: h.w}
+}
+
+func (h *writeLevelHandler) WithGroup(name string) slog.Handler {
+	return &writeLevelHandler{Handler: h.Handler.WithGroup(name), w: h.w}
+}
+
+// levelHandler drops records below level before they reach a handler passed
+// to WithHandler, which may have its own, lower, level
+type levelHandler struct {
+	slog.Handler
+	level slog.Leveler
+}
+
+func (h *levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level() && h.Handler.Enabled(ctx, level)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{Handler: h.Handler.WithAttrs(attrs), level: h.level}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{Handler: h.Handler.WithGroup(name), level: h.level}
+}
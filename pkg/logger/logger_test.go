@@ -6,7 +6,9 @@ import (
 	"io"
 	"log/slog"
 	"os"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -239,6 +241,9 @@ func TestLogAttributes(t *testing.T) {
 }
 
 func TestDefaultLogger(t *testing.T) {
+	oldDefault := slog.Default()
+	defer slog.SetDefault(oldDefault)
+
 	oldStdout := os.Stdout
 	defer func() { os.Stdout = oldStdout }()
 
@@ -248,7 +253,14 @@ func TestDefaultLogger(t *testing.T) {
 	}
 	os.Stdout = w
 
-	_, err = New("info")
+	if _, err := New("info"); err != nil {
+		t.Fatalf("New returned unexpected error: %v", err)
+	}
+	if slog.Default() != oldDefault {
+		t.Fatal("expected New not to replace the default logger")
+	}
+
+	_, err = New("info", AsDefault())
 	if err != nil {
 		t.Fatalf("New returned unexpected error: %v", err)
 	}
@@ -277,3 +289,175 @@ func TestDefaultLogger(t *testing.T) {
 		t.Errorf("expected message \"default logger test\", got %v", msg)
 	}
 }
+
+func TestNewFromOptions(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := NewFromOptions(WithLevel("warn"), WithWriter(&buf), WithFormat(FormatText))
+	if err != nil {
+		t.Fatalf("NewFromOptions returned unexpected error: %v", err)
+	}
+
+	logger.Info("hidden")
+	logger.Warn("visible")
+	if strings.Contains(buf.String(), "hidden") || !strings.Contains(buf.String(), "msg=visible") {
+		t.Errorf("expected only the warning in text format, got: %s", buf.String())
+	}
+
+	if _, err := NewFromOptions(WithLevel("trace")); err == nil {
+		t.Error("expected invalid level to return an error")
+	}
+}
+
+func TestWithHandler(t *testing.T) {
+	var buf bytes.Buffer
+	h := slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})
+
+	var lv slog.LevelVar
+	logger, err := NewFromOptions(WithHandler(h), WithLevelVar(&lv), WithFormat("ignored"))
+	if err != nil {
+		t.Fatalf("NewFromOptions returned unexpected error: %v", err)
+	}
+
+	logger.Debug("below logger level")
+	logger.Info("logged")
+	lv.Set(slog.LevelDebug)
+	logger.Debug("after level change")
+
+	output := buf.String()
+	if strings.Contains(output, "below logger level") {
+		t.Errorf("expected the logger level to apply to the handler: %s", output)
+	}
+	if !strings.Contains(output, `"msg":"logged"`) || !strings.Contains(output, "after level change") {
+		t.Errorf("expected records to reach the handler: %s", output)
+	}
+}
+
+// recordingLevelWriter records the level each record was written at
+type recordingLevelWriter struct {
+	sync.Mutex
+	level  slog.Level
+	levels []slog.Level
+}
+
+func (w *recordingLevelWriter) SetLevel(level slog.Level) {
+	w.level = level
+}
+
+func (w *recordingLevelWriter) Write(p []byte) (int, error) {
+	w.levels = append(w.levels, w.level)
+	return len(p), nil
+}
+
+func TestLevelWriter(t *testing.T) {
+	var w recordingLevelWriter
+	logger, err := NewFromOptions(WithLevel("debug"), WithWriter(&w), WithFormat("text"), WithRedaction())
+	if err != nil {
+		t.Fatalf("NewFromOptions returned unexpected error: %v", err)
+	}
+
+	logger.Debug("debug")
+	logger.With("shard", 1).Info("info")
+	logger.Warn("warn")
+	logger.WithGroup("db").Error("error")
+
+	expected := []slog.Level{slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError}
+	if !slices.Equal(w.levels, expected) {
+		t.Errorf("expected records to be written at %v, got %v", expected, w.levels)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := map[string]slog.Level{
+		"debug": slog.LevelDebug,
+		"INFO":  slog.LevelInfo,
+		"Warn":  slog.LevelWarn,
+		"error": slog.LevelError,
+	}
+	for name, expected := range tests {
+		level, err := ParseLevel(name)
+		if err != nil {
+			t.Fatalf("ParseLevel(%q) returned unexpected error: %v", name, err)
+		}
+		if level != expected {
+			t.Errorf("ParseLevel(%q) = %v, expected %v", name, level, expected)
+		}
+	}
+
+	if _, err := ParseLevel("trace"); err == nil {
+		t.Error("ParseLevel(\"trace\") should have returned an error")
+	}
+}
+
+func TestWithLevelVar(t *testing.T) {
+	oldStdout := os.Stdout
+	defer func() { os.Stdout = oldStdout }()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	os.Stdout = w
+
+	var lv slog.LevelVar
+	logger, err := New("info", WithLevelVar(&lv))
+	if err != nil {
+		t.Fatalf("New returned unexpected error: %v", err)
+	}
+	if lv.Level() != slog.LevelInfo {
+		t.Errorf("expected level var to be set to info, got %v", lv.Level())
+	}
+
+	logger.Debug("hidden")
+	lv.Set(slog.LevelDebug)
+	logger.Debug("visible")
+
+	w.Close()
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, r); err != nil {
+		t.Fatalf("failed to read captured output: %v", err)
+	}
+
+	output := buf.String()
+	if strings.Contains(output, "hidden") {
+		t.Errorf("expected debug message before level change to be dropped: %s", output)
+	}
+	if !strings.Contains(output, "visible") {
+		t.Errorf("expected debug message after level change to be logged: %s", output)
+	}
+}
+
+func TestWithFormat(t *testing.T) {
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{FormatJSON, `"msg":"formatted"`},
+		{FormatText, `msg=formatted`},
+		{FormatPretty, `INF formatted`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			t.Setenv("NO_COLOR", "1")
+
+			var buf bytes.Buffer
+			logger, err := New("info", WithFormat(tt.format), WithWriter(&buf))
+			if err != nil {
+				t.Fatalf("New returned unexpected error: %v", err)
+			}
+			logger.Info("formatted", "key", "value")
+
+			if !strings.Contains(buf.String(), tt.expected) {
+				t.Errorf("expected output to contain %q, got: %s", tt.expected, buf.String())
+			}
+			if !strings.Contains(buf.String(), "key") {
+				t.Errorf("expected output to contain attribute, got: %s", buf.String())
+			}
+		})
+	}
+
+	if _, err := New("info", WithFormat("xml")); err == nil || !strings.Contains(err.Error(), "invalid log format") {
+		t.Errorf("expected invalid log format error, got: %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultMaxSize    = 100 << 20
+	defaultMaxBackups = 5
+)
+
+// OpenOutput opens the log destination described by spec:
+//
+//	stdout (the default) or stderr
+//	file:///var/log/app.log?max_size=100MB&max_backups=5&max_age=168h
+//	syslog, or syslog:///dev/log for a specific unix socket
+//
+// Files are rotated when they reach max_size, keeping at most max_backups
+// rotated files no older than max_age. The returned writer must be closed
+// when the service stops.
+func OpenOutput(spec string) (io.WriteCloser, error) {
+	switch spec {
+	case "", "stdout":
+		return nopCloser{os.Stdout}, nil
+	case "stderr":
+		return nopCloser{os.Stderr}, nil
+	case "syslog":
+		return openSyslog("")
+	}
+
+	u, err := url.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid log output %q: %w", spec, err)
+	}
+
+	switch u.Scheme {
+	case "file":
+		return openFileOutput(u)
+	case "syslog":
+		return openSyslog(u.Path)
+	default:
+		return nil, fmt.Errorf("invalid log output %q: expected stdout, stderr, file:// or syslog://", spec)
+	}
+}
+
+func openFileOutput(u *url.URL) (io.WriteCloser, error) {
+	if u.Host != "" && u.Host != "localhost" {
+		return nil, fmt.Errorf("invalid log file %q: remote hosts are not supported", u.String())
+	}
+	if u.Path == "" {
+		return nil, fmt.Errorf("invalid log file %q: missing path", u.String())
+	}
+
+	f := &RotatingFile{
+		Path:       u.Path,
+		MaxSize:    defaultMaxSize,
+		MaxBackups: defaultMaxBackups,
+	}
+
+	query := u.Query()
+	if v := query.Get("max_size"); v != "" {
+		size, err := parseSize(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid max_size in log output: %w", err)
+		}
+		f.MaxSize = size
+	}
+	if v := query.Get("max_backups"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid max_backups in log output: %q", v)
+		}
+		f.MaxBackups = n
+	}
+	if v := query.Get("max_age"); v != "" {
+		age, err := time.ParseDuration(v)
+		if err != nil || age < 0 {
+			return nil, fmt.Errorf("invalid max_age in log output: %q", v)
+		}
+		f.MaxAge = age
+	}
+
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// parseSize parses a byte size with an optional KB, MB or GB suffix
+func parseSize(s string) (int64, error) {
+	units := []struct {
+		suffix string
+		size   int64
+	}{
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+		{"B", 1},
+	}
+
+	upper := strings.ToUpper(strings.TrimSpace(s))
+	multiplier := int64(1)
+	for _, unit := range units {
+		if strings.HasSuffix(upper, unit.suffix) {
+			upper = strings.TrimSpace(strings.TrimSuffix(upper, unit.suffix))
+			multiplier = unit.size
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(upper, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * multiplier, nil
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
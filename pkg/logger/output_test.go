@@ -0,0 +1,143 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOpenOutput(t *testing.T) {
+	for _, spec := range []string{"", "stdout", "stderr"} {
+		w, err := OpenOutput(spec)
+		if err != nil {
+			t.Fatalf("OpenOutput(%q) returned unexpected error: %v", spec, err)
+		}
+		if err := w.Close(); err != nil {
+			t.Errorf("closing %q output returned unexpected error: %v", spec, err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "logs", "app.log")
+	w, err := OpenOutput("file://" + path + "?max_size=10KB&max_backups=2&max_age=24h")
+	if err != nil {
+		t.Fatalf("OpenOutput returned unexpected error: %v", err)
+	}
+	defer w.Close()
+
+	f, ok := w.(*RotatingFile)
+	if !ok {
+		t.Fatalf("expected *RotatingFile, got %T", w)
+	}
+	if f.Path != path || f.MaxSize != 10<<10 || f.MaxBackups != 2 || f.MaxAge != 24*time.Hour {
+		t.Errorf("unexpected rotating file settings: %+v", f)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("expected log file to be created: %v", err)
+	}
+
+	// Plain syslog is valid, though there may be no daemon to connect to
+	if w, err := OpenOutput("syslog"); err == nil {
+		w.Close()
+	} else if !strings.Contains(err.Error(), "syslog") || strings.Contains(err.Error(), "invalid log output") {
+		t.Errorf("expected syslog to be a valid output, got %v", err)
+	}
+
+	invalid := []string{
+		"kafka://broker",
+		"file://",
+		"file://remote/var/log/app.log",
+		"file:///tmp/app.log?max_size=big",
+		"file:///tmp/app.log?max_backups=-1",
+		"file:///tmp/app.log?max_age=1y",
+	}
+	for _, spec := range invalid {
+		if _, err := OpenOutput(spec); err == nil {
+			t.Errorf("OpenOutput(%q) should have returned an error", spec)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{
+		"512":   512,
+		"10KB":  10 << 10,
+		"100MB": 100 << 20,
+		"1gb":   1 << 30,
+		"64 MB": 64 << 20,
+		"2048B": 2048,
+	}
+	for input, expected := range tests {
+		size, err := parseSize(input)
+		if err != nil {
+			t.Fatalf("parseSize(%q) returned unexpected error: %v", input, err)
+		}
+		if size != expected {
+			t.Errorf("parseSize(%q) = %d, expected %d", input, size, expected)
+		}
+	}
+
+	for _, input := range []string{"", "MB", "-1MB", "0", "1TB"} {
+		if _, err := parseSize(input); err == nil {
+			t.Errorf("parseSize(%q) should have returned an error", input)
+		}
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	f := &RotatingFile{Path: path, MaxSize: 20, MaxBackups: 2}
+	defer f.Close()
+
+	for i := 0; i < 5; i++ {
+		if _, err := f.Write([]byte(strings.Repeat("x", 15) + "\n")); err != nil {
+			t.Fatalf("write %d returned unexpected error: %v", i, err)
+		}
+	}
+
+	backups, _ := filepath.Glob(path + ".*")
+	if len(backups) != 2 {
+		t.Errorf("expected 2 backups to be kept, got %d: %v", len(backups), backups)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	if len(data) != 16 {
+		t.Errorf("expected current log file to hold the last write, got %d bytes", len(data))
+	}
+}
+
+func TestRotatingFileMaxAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	old := path + "." + time.Now().Add(-48*time.Hour).UTC().Format(backupTimeFormat)
+	if err := os.WriteFile(old, []byte("old\n"), 0o644); err != nil {
+		t.Fatalf("failed to write old backup: %v", err)
+	}
+	unrelated := path + ".keep"
+	if err := os.WriteFile(unrelated, []byte("keep\n"), 0o644); err != nil {
+		t.Fatalf("failed to write unrelated file: %v", err)
+	}
+
+	f := &RotatingFile{Path: path, MaxAge: 24 * time.Hour}
+	defer f.Close()
+	if _, err := f.Write([]byte("line\n")); err != nil {
+		t.Fatalf("write returned unexpected error: %v", err)
+	}
+	if err := f.Rotate(); err != nil {
+		t.Fatalf("Rotate returned unexpected error: %v", err)
+	}
+
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Errorf("expected backup older than max age to be removed, got: %v", err)
+	}
+	if _, err := os.Stat(unrelated); err != nil {
+		t.Errorf("expected unrelated file to be kept: %v", err)
+	}
+	backups, _ := filepath.Glob(path + ".2*")
+	if len(backups) != 1 {
+		t.Errorf("expected the new backup to be kept, got: %v", backups)
+	}
+}
@@ -0,0 +1,199 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const (
+	colourReset  = "\033[0m"
+	colourDim    = "\033[2m"
+	colourRed    = "\033[31m"
+	colourYellow = "\033[33m"
+	colourBlue   = "\033[34m"
+	colourCyan   = "\033[36m"
+)
+
+// PrettyHandler writes human readable, colourised records for local
+// development, e.g.
+//
+//	15:04:05.000 INF starting service=order port=8002
+//
+// Colours are only used when writing to a terminal, and not when the
+// NO_COLOR environment variable is set.
+type PrettyHandler struct {
+	opts   slog.HandlerOptions
+	colour bool
+	prefix string // pre-formatted attributes from WithAttrs
+	groups []string
+
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+// NewPrettyHandler returns a handler writing to w. If opts is nil, the
+// default options are used.
+func NewPrettyHandler(w io.Writer, opts *slog.HandlerOptions) *PrettyHandler {
+	h := &PrettyHandler{
+		colour: os.Getenv("NO_COLOR") == "" && isTerminal(w),
+		mu:     &sync.Mutex{},
+		w:      w,
+	}
+	if opts != nil {
+		h.opts = *opts
+	}
+	return h
+}
+
+// isTerminal reports whether w writes to a terminal rather than a file or
+// pipe
+func isTerminal(w io.Writer) bool {
+	if n, ok := w.(nopCloser); ok {
+		w = n.Writer
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func (h *PrettyHandler) Enabled(_ context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+	return level >= minLevel
+}
+
+func (h *PrettyHandler) Handle(_ context.Context, r slog.Record) error {
+	var buf bytes.Buffer
+
+	if !r.Time.IsZero() {
+		h.paint(&buf, colourDim, r.Time.Format("15:04:05.000"))
+		buf.WriteByte(' ')
+	}
+
+	levelColour, levelText := levelStyle(r.Level)
+	h.paint(&buf, levelColour, levelText)
+	buf.WriteByte(' ')
+	buf.WriteString(r.Message)
+
+	buf.WriteString(h.prefix)
+	r.Attrs(func(a slog.Attr) bool {
+		h.appendAttr(&buf, h.groups, a)
+		return true
+	})
+	buf.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(buf.Bytes())
+	return err
+}
+
+func (h *PrettyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var buf bytes.Buffer
+	for _, a := range attrs {
+		h.appendAttr(&buf, h.groups, a)
+	}
+
+	h2 := *h
+	h2.prefix = h.prefix + buf.String()
+	return &h2
+}
+
+func (h *PrettyHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.groups = append(slices.Clip(h.groups), name)
+	return &h2
+}
+
+func (h *PrettyHandler) appendAttr(buf *bytes.Buffer, groups []string, a slog.Attr) {
+	if h.opts.ReplaceAttr != nil && a.Value.Kind() != slog.KindGroup {
+		a = h.opts.ReplaceAttr(groups, a)
+	}
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		nested := groups
+		if a.Key != "" {
+			nested = append(slices.Clip(groups), a.Key)
+		}
+		for _, ga := range a.Value.Group() {
+			h.appendAttr(buf, nested, ga)
+		}
+		return
+	}
+
+	buf.WriteByte(' ')
+	key := a.Key
+	for i := len(groups) - 1; i >= 0; i-- {
+		key = groups[i] + "." + key
+	}
+	h.paint(buf, colourCyan, key+"=")
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		buf.WriteString(quoteIfNeeded(a.Value.String()))
+	case slog.KindTime:
+		buf.WriteString(a.Value.Time().Format(time.RFC3339Nano))
+	default:
+		if err, ok := a.Value.Any().(error); ok {
+			h.paint(buf, colourRed, quoteIfNeeded(err.Error()))
+			return
+		}
+		buf.WriteString(quoteIfNeeded(a.Value.String()))
+	}
+}
+
+// quoteIfNeeded quotes values which would otherwise be ambiguous when read
+func quoteIfNeeded(s string) string {
+	if s == "" {
+		return `""`
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == 0x7f || !unicode.IsPrint(r) {
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}
+
+func (h *PrettyHandler) paint(buf *bytes.Buffer, colour, s string) {
+	if !h.colour {
+		buf.WriteString(s)
+		return
+	}
+	buf.WriteString(colour)
+	buf.WriteString(s)
+	buf.WriteString(colourReset)
+}
+
+func levelStyle(level slog.Level) (string, string) {
+	switch {
+	case level >= slog.LevelError:
+		return colourRed, "ERR"
+	case level >= slog.LevelWarn:
+		return colourYellow, "WRN"
+	case level >= slog.LevelInfo:
+		return colourBlue, "INF"
+	default:
+		return colourDim, "DBG"
+	}
+}
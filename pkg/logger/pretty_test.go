@@ -0,0 +1,58 @@
+package logger
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPrettyHandler(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+
+	var buf bytes.Buffer
+	logger := slog.New(NewPrettyHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	logger.With("service", "billing").WithGroup("http").Debug("request", "method", "GET", "path", "/a b")
+
+	line := buf.String()
+	for _, expected := range []string{"DBG request", "service=billing", "http.method=GET", `http.path="/a b"`} {
+		if !strings.Contains(line, expected) {
+			t.Errorf("expected %q in output, got: %s", expected, line)
+		}
+	}
+	if strings.Contains(line, "\x1b[") {
+		t.Errorf("expected no colour codes with NO_COLOR set, got: %q", line)
+	}
+	if !strings.HasSuffix(line, "\n") || strings.Count(line, "\n") != 1 {
+		t.Errorf("expected a single line, got: %q", line)
+	}
+}
+
+func TestPrettyHandlerColour(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+
+	var buf bytes.Buffer
+	h := NewPrettyHandler(&buf, nil)
+	slog.New(h).Error("failed")
+	if strings.Contains(buf.String(), "\x1b[") {
+		t.Errorf("expected no colour codes when not writing to a terminal, got: %q", buf.String())
+	}
+
+	buf.Reset()
+	h.colour = true
+	slog.New(h).Error("failed")
+	if !strings.Contains(buf.String(), "\x1b[") {
+		t.Errorf("expected colour codes in output, got: %q", buf.String())
+	}
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "log"))
+	if err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	defer f.Close()
+	if isTerminal(f) || isTerminal(nopCloser{f}) {
+		t.Error("expected a file not to be a terminal")
+	}
+}
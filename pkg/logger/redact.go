@@ -0,0 +1,259 @@
+package logger
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Redacted replaces masked values
+const Redacted = "[REDACTED]"
+
+// DefaultRedactKeys are the attribute key patterns whose values are always
+// redacted. Patterns use path.Match syntax and are case insensitive.
+var DefaultRedactKeys = []string{
+	"*password*",
+	"*passwd*",
+	"*secret*",
+	"*token*",
+	"*api_key*",
+	"*apikey*",
+	"authorization",
+	"cookie",
+	"set-cookie",
+	"card_number",
+	"pan",
+	"cvv",
+	"cvc",
+	"ssn",
+}
+
+// Detector finds sensitive values inside strings, whatever their key
+type Detector struct {
+	Name    string
+	Pattern *regexp.Regexp
+	// Valid filters matches of Pattern, e.g. by checksum. Nil accepts all.
+	Valid func(match string) bool
+}
+
+// Built-in detectors
+var (
+	// BearerDetector finds bearer tokens, e.g. in Authorization header values
+	BearerDetector = Detector{
+		Name:    "bearer",
+		Pattern: regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9\-._~+/]+=*`),
+	}
+	// JWTDetector finds JSON Web Tokens
+	JWTDetector = Detector{
+		Name:    "jwt",
+		Pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`),
+	}
+	// PANDetector finds card numbers of 13 to 19 digits, optionally separated
+	// by spaces or dashes, which pass the Luhn check
+	PANDetector = Detector{
+		Name:    "pan",
+		Pattern: regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+		Valid:   luhnValid,
+	}
+	// EmailDetector finds email addresses
+	EmailDetector = Detector{
+		Name:    "email",
+		Pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+	}
+)
+
+// DefaultDetectors returns the built-in detectors. Bearer tokens are matched
+// before JWTs so the whole header value is replaced.
+func DefaultDetectors() []Detector {
+	return []Detector{BearerDetector, JWTDetector, PANDetector, EmailDetector}
+}
+
+// RedactOption configures a RedactHandler
+type RedactOption func(*redactor)
+
+// RedactKeys replaces DefaultRedactKeys with patterns
+func RedactKeys(patterns ...string) RedactOption {
+	return func(r *redactor) {
+		r.keys = patterns
+	}
+}
+
+// RedactDetectors replaces DefaultDetectors with detectors
+func RedactDetectors(detectors ...Detector) RedactOption {
+	return func(r *redactor) {
+		r.detectors = detectors
+	}
+}
+
+// RedactHash replaces sensitive values with a keyed SHA-256 hash rather than
+// masking them, so the same value can be correlated across records without
+// being revealed
+func RedactHash(key []byte) RedactOption {
+	return func(r *redactor) {
+		r.hashKey = key
+		r.hash = true
+	}
+}
+
+// RedactHandler redacts attributes matching the key patterns, and values
+// found by the detectors in the message and attributes, before passing
+// records to the wrapped handler. Groups are redacted recursively.
+type RedactHandler struct {
+	next slog.Handler
+	r    *redactor
+}
+
+// NewRedactHandler wraps next, using DefaultRedactKeys and DefaultDetectors
+// unless they are replaced by opts
+func NewRedactHandler(next slog.Handler, opts ...RedactOption) *RedactHandler {
+	r := &redactor{
+		keys:      DefaultRedactKeys,
+		detectors: DefaultDetectors(),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	keys := make([]string, len(r.keys))
+	for i, key := range r.keys {
+		keys[i] = strings.ToLower(key)
+	}
+	r.keys = keys
+	return &RedactHandler{next: next, r: r}
+}
+
+func (h *RedactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *RedactHandler) Handle(ctx context.Context, r slog.Record) error {
+	redacted := slog.NewRecord(r.Time, r.Level, h.r.redactString(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		redacted.AddAttrs(h.r.redactAttr(a))
+		return true
+	})
+	return h.next.Handle(ctx, redacted)
+}
+
+func (h *RedactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = h.r.redactAttr(a)
+	}
+	return &RedactHandler{next: h.next.WithAttrs(redacted), r: h.r}
+}
+
+func (h *RedactHandler) WithGroup(name string) slog.Handler {
+	return &RedactHandler{next: h.next.WithGroup(name), r: h.r}
+}
+
+type redactor struct {
+	keys      []string
+	detectors []Detector
+	hash      bool
+	hashKey   []byte
+}
+
+func (r *redactor) redactAttr(a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+
+	if r.sensitiveKey(a.Key) {
+		return slog.String(a.Key, r.replace(a.Value.String()))
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindGroup:
+		group := a.Value.Group()
+		redacted := make([]slog.Attr, len(group))
+		for i, ga := range group {
+			redacted[i] = r.redactAttr(ga)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(redacted...)}
+	case slog.KindString:
+		return slog.String(a.Key, r.redactString(a.Value.String()))
+	case slog.KindInt64, slog.KindUint64:
+		// Card numbers fit in an int64, so numbers are checked as strings
+		s := a.Value.String()
+		if redacted := r.redactString(s); redacted != s {
+			return slog.String(a.Key, redacted)
+		}
+	case slog.KindAny:
+		// Structs, maps and errors are checked in their printed form, and
+		// replaced by the redacted string if anything is found
+		var s string
+		if err, ok := a.Value.Any().(error); ok {
+			s = err.Error()
+		} else {
+			s = fmt.Sprintf("%+v", a.Value.Any())
+		}
+		if redacted := r.redactString(s); redacted != s {
+			return slog.String(a.Key, redacted)
+		}
+	}
+	return a
+}
+
+func (r *redactor) sensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, pattern := range r.keys {
+		if matched, _ := path.Match(pattern, key); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// redactString replaces every detected value in s
+func (r *redactor) redactString(s string) string {
+	for _, d := range r.detectors {
+		s = d.Pattern.ReplaceAllStringFunc(s, func(match string) string {
+			if d.Valid != nil && !d.Valid(match) {
+				return match
+			}
+			return r.replace(match)
+		})
+	}
+	return s
+}
+
+func (r *redactor) replace(value string) string {
+	if !r.hash {
+		return Redacted
+	}
+	mac := hmac.New(sha256.New, r.hashKey)
+	mac.Write([]byte(value))
+	return "sha256:" + hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// luhnValid reports whether the digits in s, ignoring separators, are a
+// plausible card number
+func luhnValid(s string) bool {
+	digits := strings.NewReplacer(" ", "", "-", "").Replace(s)
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d, err := strconv.Atoi(digits[i : i+1])
+		if err != nil {
+			return false
+		}
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
@@ -0,0 +1,145 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const backupTimeFormat = "20060102T150405.000000000"
+
+// RotatingFile is an io.WriteCloser which writes to Path, renaming it with a
+// timestamp suffix once it would grow past MaxSize bytes. Only the newest
+// MaxBackups rotated files are kept, and those older than MaxAge are removed.
+// Writing after Close reopens the file.
+type RotatingFile struct {
+	Path       string
+	MaxSize    int64
+	MaxBackups int
+	MaxAge     time.Duration
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+func (f *RotatingFile) Write(b []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	if f.MaxSize > 0 && f.size > 0 && f.size+int64(len(b)) > f.MaxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(b)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// Rotate closes the current file and starts a new one
+func (f *RotatingFile) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rotate()
+}
+
+func (f *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0o755); err != nil {
+		return fmt.Errorf("creating log directory: %w", err)
+	}
+
+	file, err := os.OpenFile(f.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("opening log file: %w", err)
+	}
+
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+func (f *RotatingFile) rotate() error {
+	if f.file != nil {
+		if err := f.file.Close(); err != nil {
+			return fmt.Errorf("closing log file: %w", err)
+		}
+		f.file = nil
+	}
+
+	backup := f.Path + "." + time.Now().UTC().Format(backupTimeFormat)
+	if err := os.Rename(f.Path, backup); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("rotating log file: %w", err)
+	}
+
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	return f.prune()
+}
+
+// prune removes rotated files beyond MaxBackups or older than MaxAge
+func (f *RotatingFile) prune() error {
+	backups, err := filepath.Glob(f.Path + ".*")
+	if err != nil {
+		return err
+	}
+
+	// Timestamps sort lexically, so the newest backups are last
+	backups = slices.DeleteFunc(backups, func(path string) bool {
+		_, err := time.Parse(backupTimeFormat, strings.TrimPrefix(path, f.Path+"."))
+		return err != nil
+	})
+	slices.Sort(backups)
+
+	var remove []string
+	if f.MaxBackups > 0 && len(backups) > f.MaxBackups {
+		remove = append(remove, backups[:len(backups)-f.MaxBackups]...)
+		backups = backups[len(backups)-f.MaxBackups:]
+	}
+	if f.MaxAge > 0 {
+		cutoff := time.Now().UTC().Add(-f.MaxAge)
+		for _, path := range backups {
+			rotated, _ := time.Parse(backupTimeFormat, strings.TrimPrefix(path, f.Path+"."))
+			if rotated.Before(cutoff) {
+				remove = append(remove, path)
+			}
+		}
+	}
+
+	for _, path := range remove {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("removing old log file: %w", err)
+		}
+	}
+	return nil
+}
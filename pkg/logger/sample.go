@@ -0,0 +1,251 @@
+package logger
+
+import (
+	"context"
+	"hash/fnv"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Sampling defaults
+const (
+	DefaultSampleFirst      = 10
+	DefaultSampleThereafter = 100
+	DefaultDedupWindow      = time.Second
+	DefaultSummaryInterval  = time.Minute
+)
+
+// SampleOption configures a SamplingHandler
+type SampleOption func(*sampler)
+
+// SampleFirst sets how many records with the same message are logged each
+// second before sampling starts
+func SampleFirst(n int) SampleOption {
+	return func(s *sampler) {
+		s.first = n
+	}
+}
+
+// SampleThereafter logs every mth record with the same message once the first
+// have been logged in a second. Zero drops them all.
+func SampleThereafter(m int) SampleOption {
+	return func(s *sampler) {
+		s.thereafter = m
+	}
+}
+
+// SampleDedupWindow drops records identical to one logged less than d ago.
+// Zero disables deduplication.
+func SampleDedupWindow(d time.Duration) SampleOption {
+	return func(s *sampler) {
+		s.dedupWindow = d
+	}
+}
+
+// SampleSummaryInterval sets how often a summary of dropped records is logged
+func SampleSummaryInterval(d time.Duration) SampleOption {
+	return func(s *sampler) {
+		s.summaryInterval = d
+	}
+}
+
+// SamplingHandler limits how many debug and info records reach the wrapped
+// handler. Identical records within the dedup window are dropped, then for
+// each message the first records in every second are logged and after that
+// only every mth. Warnings and errors are never dropped.
+//
+// When records have been dropped, a "log entries dropped" warning with the
+// counts is logged once the summary interval has passed.
+type SamplingHandler struct {
+	next  slog.Handler
+	scope string // groups and attributes added by WithGroup and WithAttrs
+	s     *sampler
+}
+
+// NewSamplingHandler wraps next, using the Default* settings unless they are
+// replaced by opts
+func NewSamplingHandler(next slog.Handler, opts ...SampleOption) *SamplingHandler {
+	s := &sampler{
+		root:            next,
+		first:           DefaultSampleFirst,
+		thereafter:      DefaultSampleThereafter,
+		dedupWindow:     DefaultDedupWindow,
+		summaryInterval: DefaultSummaryInterval,
+		now:             time.Now,
+		seen:            make(map[uint64]time.Time),
+		counts:          make(map[string]*messageCount),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return &SamplingHandler{next: next, s: s}
+}
+
+func (h *SamplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *SamplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level < slog.LevelWarn && !h.s.allow(h.scope, r) {
+		return nil
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *SamplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	scope := h.scope
+	for _, a := range attrs {
+		scope += " " + a.String()
+	}
+	return &SamplingHandler{next: h.next.WithAttrs(attrs), scope: scope, s: h.s}
+}
+
+func (h *SamplingHandler) WithGroup(name string) slog.Handler {
+	return &SamplingHandler{next: h.next.WithGroup(name), scope: h.scope + " " + name + ".", s: h.s}
+}
+
+// Flush logs the summary of dropped records now, rather than waiting for the
+// summary interval
+func (h *SamplingHandler) Flush() {
+	h.s.summarise()
+}
+
+type messageCount struct {
+	start time.Time
+	n     int
+}
+
+type sampler struct {
+	root            slog.Handler
+	first           int
+	thereafter      int
+	dedupWindow     time.Duration
+	summaryInterval time.Duration
+	now             func() time.Time
+
+	mu           sync.Mutex
+	seen         map[uint64]time.Time
+	counts       map[string]*messageCount
+	deduplicated int
+	sampled      int
+	since        time.Time
+	timer        *time.Timer
+	pruned       time.Time
+}
+
+// allow reports whether a record should be logged, counting it if not
+func (s *sampler) allow(scope string, r slog.Record) bool {
+	now := s.now()
+
+	var key uint64
+	if s.dedupWindow > 0 {
+		key = recordKey(scope, r)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Forgetting old records here as well as in summarise keeps the maps
+	// bounded when nothing is dropped, e.g. for unique messages
+	if now.Sub(s.pruned) >= max(s.dedupWindow, time.Second) {
+		s.prune(now)
+	}
+
+	if s.dedupWindow > 0 {
+		if last, ok := s.seen[key]; ok && now.Sub(last) < s.dedupWindow {
+			s.deduplicated++
+			s.scheduleSummary(now)
+			return false
+		}
+		s.seen[key] = now
+	}
+
+	c, ok := s.counts[r.Message]
+	if !ok || now.Sub(c.start) >= time.Second {
+		c = &messageCount{start: now}
+		s.counts[r.Message] = c
+	}
+	c.n++
+	if c.n <= s.first {
+		return true
+	}
+	if s.thereafter > 0 && (c.n-s.first)%s.thereafter == 0 {
+		return true
+	}
+
+	s.sampled++
+	s.scheduleSummary(now)
+	return false
+}
+
+// scheduleSummary starts the summary timer on the first drop of an interval
+func (s *sampler) scheduleSummary(now time.Time) {
+	if s.timer != nil {
+		return
+	}
+	s.since = now
+	s.timer = time.AfterFunc(s.summaryInterval, s.summarise)
+}
+
+// summarise logs the dropped counts and forgets records which can no longer
+// be deduplicated or sampled
+func (s *sampler) summarise() {
+	s.mu.Lock()
+	now := s.now()
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	deduplicated, sampled, since := s.deduplicated, s.sampled, s.since
+	s.deduplicated, s.sampled = 0, 0
+	s.prune(now)
+	s.mu.Unlock()
+
+	if deduplicated+sampled == 0 {
+		return
+	}
+
+	r := slog.NewRecord(now, slog.LevelWarn, "log entries dropped", 0)
+	r.AddAttrs(
+		slog.Int("dropped", deduplicated+sampled),
+		slog.Int("deduplicated", deduplicated),
+		slog.Int("sampled", sampled),
+		slog.Duration("period", now.Sub(since)),
+	)
+	if s.root.Enabled(context.Background(), r.Level) {
+		_ = s.root.Handle(context.Background(), r)
+	}
+}
+
+// prune forgets records which can no longer be deduplicated or sampled. It
+// must be called with s.mu held.
+func (s *sampler) prune(now time.Time) {
+	for key, last := range s.seen {
+		if now.Sub(last) >= s.dedupWindow {
+			delete(s.seen, key)
+		}
+	}
+	for msg, c := range s.counts {
+		if now.Sub(c.start) >= time.Second {
+			delete(s.counts, msg)
+		}
+	}
+	s.pruned = now
+}
+
+// recordKey hashes everything that makes a record distinct apart from its time
+func recordKey(scope string, r slog.Record) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(scope))
+	h.Write([]byte{0})
+	h.Write([]byte(r.Level.String()))
+	h.Write([]byte{0})
+	h.Write([]byte(r.Message))
+	r.Attrs(func(a slog.Attr) bool {
+		h.Write([]byte{0})
+		h.Write([]byte(a.String()))
+		return true
+	})
+	return h.Sum64()
+}
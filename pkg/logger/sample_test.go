@@ -0,0 +1,218 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type safeBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *safeBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *safeBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+func newSamplingLogger(opts ...SampleOption) (*slog.Logger, *SamplingHandler, *fakeClock, *bytes.Buffer) {
+	var buf bytes.Buffer
+	clock := &fakeClock{t: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	h := NewSamplingHandler(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}), opts...)
+	h.s.now = clock.now
+	return slog.New(h), h, clock, &buf
+}
+
+func countLines(buf *bytes.Buffer, msg string) int {
+	return strings.Count(buf.String(), `"msg":"`+msg+`"`)
+}
+
+func TestSamplingHandlerSampling(t *testing.T) {
+	logger, _, clock, buf := newSamplingLogger(SampleFirst(3), SampleThereafter(5), SampleDedupWindow(0))
+
+	for i := range 23 {
+		logger.Info("retrying", "attempt", i)
+	}
+	// The first 3, then the 8th, 13th, 18th and 23rd
+	if got := countLines(buf, "retrying"); got != 7 {
+		t.Errorf("expected 7 records, got %d", got)
+	}
+
+	buf.Reset()
+	clock.advance(time.Second)
+	for i := range 3 {
+		logger.Info("retrying", "attempt", i)
+	}
+	if got := countLines(buf, "retrying"); got != 3 {
+		t.Errorf("expected counts to reset each second, got %d records", got)
+	}
+}
+
+func TestSamplingHandlerPerMessage(t *testing.T) {
+	logger, _, _, buf := newSamplingLogger(SampleFirst(2), SampleThereafter(0), SampleDedupWindow(0))
+
+	for i := range 5 {
+		logger.Info("first message", "i", i)
+		logger.Info("second message", "i", i)
+	}
+	if countLines(buf, "first message") != 2 || countLines(buf, "second message") != 2 {
+		t.Errorf("expected each message to be sampled separately: %s", buf.String())
+	}
+}
+
+func TestSamplingHandlerDedup(t *testing.T) {
+	logger, _, clock, buf := newSamplingLogger(SampleDedupWindow(time.Second))
+
+	for range 5 {
+		logger.Info("cache miss", "key", "a")
+	}
+	logger.Info("cache miss", "key", "b")
+	logger.With("shard", 2).Info("cache miss", "key", "a")
+	if got := countLines(buf, "cache miss"); got != 3 {
+		t.Errorf("expected identical records to be dropped, got %d records: %s", got, buf.String())
+	}
+
+	clock.advance(time.Second)
+	logger.Info("cache miss", "key", "a")
+	if got := countLines(buf, "cache miss"); got != 4 {
+		t.Errorf("expected record to be logged again after the window, got %d records", got)
+	}
+}
+
+func TestSamplingHandlerForgetsOldRecords(t *testing.T) {
+	logger, h, clock, buf := newSamplingLogger(SampleDedupWindow(time.Second))
+
+	// Nothing is dropped, so no summary ever prunes the records
+	for i := range 1000 {
+		logger.Info("request "+strconv.Itoa(i), "i", i)
+		clock.advance(10 * time.Millisecond)
+	}
+	if got := strings.Count(buf.String(), "\n"); got != 1000 {
+		t.Fatalf("expected every record to be logged, got %d", got)
+	}
+
+	h.s.mu.Lock()
+	defer h.s.mu.Unlock()
+	if len(h.s.seen) > 200 || len(h.s.counts) > 200 {
+		t.Errorf("expected old records to be forgotten, remembering %d records and %d messages", len(h.s.seen), len(h.s.counts))
+	}
+}
+
+func TestSamplingHandlerWarningsPassThrough(t *testing.T) {
+	logger, _, _, buf := newSamplingLogger(SampleFirst(1), SampleThereafter(0))
+
+	for range 10 {
+		logger.Warn("disk almost full")
+		logger.Error("upstream failed")
+	}
+	if countLines(buf, "disk almost full") != 10 || countLines(buf, "upstream failed") != 10 {
+		t.Errorf("expected warnings and errors to never be dropped: %s", buf.String())
+	}
+}
+
+func TestSamplingHandlerSummary(t *testing.T) {
+	logger, h, clock, buf := newSamplingLogger(SampleFirst(1), SampleThereafter(0), SampleSummaryInterval(time.Hour))
+
+	for i := range 5 {
+		logger.Debug("polling", "i", i)
+	}
+	for range 3 {
+		logger.Debug("tick")
+	}
+	clock.advance(time.Minute)
+	h.Flush()
+
+	var summary map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("failed to parse record %q: %v", line, err)
+		}
+		if record["msg"] == "log entries dropped" {
+			summary = record
+		}
+	}
+	if summary == nil {
+		t.Fatalf("expected a summary record: %s", buf.String())
+	}
+
+	expected := map[string]any{
+		"level":        "WARN",
+		"dropped":      float64(6),
+		"deduplicated": float64(2),
+		"sampled":      float64(4),
+		"period":       float64(time.Minute),
+	}
+	for key, value := range expected {
+		if summary[key] != value {
+			t.Errorf("expected summary %s to be %v, got %v", key, value, summary[key])
+		}
+	}
+
+	buf.Reset()
+	h.Flush()
+	if buf.Len() != 0 {
+		t.Errorf("expected no summary when nothing was dropped, got: %s", buf.String())
+	}
+}
+
+func TestSamplingHandlerSummaryInterval(t *testing.T) {
+	var buf safeBuffer
+	logger := slog.New(NewSamplingHandler(slog.NewJSONHandler(&buf, nil),
+		SampleFirst(1), SampleThereafter(0), SampleSummaryInterval(10*time.Millisecond)))
+
+	for i := range 3 {
+		logger.Info("flood", "i", i)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for !strings.Contains(buf.String(), "log entries dropped") {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected a summary to be logged after the interval: %s", buf.String())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !strings.Contains(buf.String(), `"dropped":2`) {
+		t.Errorf("expected 2 dropped records in summary: %s", buf.String())
+	}
+}
+
+func TestWithSampling(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New("info", WithWriter(&buf), WithSampling(SampleFirst(1), SampleThereafter(0)))
+	if err != nil {
+		t.Fatalf("New returned unexpected error: %v", err)
+	}
+
+	for i := range 3 {
+		logger.Info("same", "i", i)
+	}
+	if got := countLines(&buf, "same"); got != 1 {
+		t.Errorf("expected 1 record, got %d", got)
+	}
+}
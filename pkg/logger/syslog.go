@@ -0,0 +1,66 @@
+//go:build !windows && !plan9
+
+package logger
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"log/syslog"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// openSyslog connects to the local syslog daemon, over the unix socket at
+// path if one is given
+func openSyslog(path string) (io.WriteCloser, error) {
+	tag := filepath.Base(os.Args[0])
+
+	var (
+		w   *syslog.Writer
+		err error
+	)
+	if path == "" {
+		w, err = syslog.New(syslog.LOG_INFO|syslog.LOG_DAEMON, tag)
+	} else {
+		w, err = syslog.Dial("unixgram", path, syslog.LOG_INFO|syslog.LOG_DAEMON, tag)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("connecting to syslog: %w", err)
+	}
+	return &syslogWriter{w: w}, nil
+}
+
+// syslogWriter writes each record at the syslog severity of its level
+type syslogWriter struct {
+	sync.Mutex
+	w     *syslog.Writer
+	level slog.Level
+}
+
+func (s *syslogWriter) SetLevel(level slog.Level) {
+	s.level = level
+}
+
+func (s *syslogWriter) Write(p []byte) (int, error) {
+	var err error
+	switch m := string(p); {
+	case s.level >= slog.LevelError:
+		err = s.w.Err(m)
+	case s.level >= slog.LevelWarn:
+		err = s.w.Warning(m)
+	case s.level >= slog.LevelInfo:
+		err = s.w.Info(m)
+	default:
+		err = s.w.Debug(m)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (s *syslogWriter) Close() error {
+	return s.w.Close()
+}
@@ -0,0 +1,12 @@
+//go:build windows || plan9
+
+package logger
+
+import (
+	"errors"
+	"io"
+)
+
+func openSyslog(string) (io.WriteCloser, error) {
+	return nil, errors.New("syslog output is not supported on this platform")
+}
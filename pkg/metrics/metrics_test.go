@@ -0,0 +1,139 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func render(t *testing.T, r *Registry) string {
+	t.Helper()
+	var b strings.Builder
+	if _, err := r.WriteTo(&b); err != nil {
+		t.Fatalf("WriteTo returned unexpected error: %v", err)
+	}
+	return b.String()
+}
+
+func TestCounter(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("orders_total", "Orders placed.", "currency")
+
+	c.Inc("GBP")
+	c.Add(2.5, "USD")
+	c.Inc("GBP")
+
+	if v := c.Value("GBP"); v != 2 {
+		t.Errorf("expected GBP counter to be 2, got %v", v)
+	}
+
+	expected := `# HELP orders_total Orders placed.
+# TYPE orders_total counter
+orders_total{currency="GBP"} 2
+orders_total{currency="USD"} 2.5
+`
+	if got := render(t, r); got != expected {
+		t.Errorf("unexpected output:\n%s\nexpected:\n%s", got, expected)
+	}
+}
+
+func TestGauge(t *testing.T) {
+	r := NewRegistry()
+	g := r.NewGauge("queue_depth", "Items waiting.")
+	g.Set(10)
+	g.Inc()
+	g.Dec()
+	g.Add(-3)
+
+	r.NewGaugeFunc("temperature", "Current temperature.", func() float64 { return 21.5 })
+
+	expected := `# HELP queue_depth Items waiting.
+# TYPE queue_depth gauge
+queue_depth 7
+# HELP temperature Current temperature.
+# TYPE temperature gauge
+temperature 21.5
+`
+	if got := render(t, r); got != expected {
+		t.Errorf("unexpected output:\n%s\nexpected:\n%s", got, expected)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogram("latency_seconds", "Latency.", []float64{1, 0.1}, "route")
+
+	h.Observe(0.05, "/a")
+	h.Observe(0.1, "/a")
+	h.Observe(0.5, "/a")
+	h.Observe(5, "/a")
+
+	if n := h.Count("/a"); n != 4 {
+		t.Errorf("expected 4 observations, got %d", n)
+	}
+
+	expected := `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/a",le="0.1"} 2
+latency_seconds_bucket{route="/a",le="1"} 3
+latency_seconds_bucket{route="/a",le="+Inf"} 4
+latency_seconds_sum{route="/a"} 5.65
+latency_seconds_count{route="/a"} 4
+`
+	if got := render(t, r); got != expected {
+		t.Errorf("unexpected output:\n%s\nexpected:\n%s", got, expected)
+	}
+}
+
+func TestEscaping(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("errors_total", "Errors\nwith \\ newline.", "msg")
+	c.Inc("say \"hi\"\n")
+
+	expected := `# HELP errors_total Errors\nwith \\ newline.
+# TYPE errors_total counter
+errors_total{msg="say \"hi\"\n"} 1
+`
+	if got := render(t, r); got != expected {
+		t.Errorf("unexpected output:\n%s\nexpected:\n%s", got, expected)
+	}
+}
+
+func TestRuntimeMetrics(t *testing.T) {
+	r := NewRegistry()
+	r.RegisterRuntimeMetrics()
+
+	output := render(t, r)
+	for _, name := range []string{"go_goroutines ", "go_memstats_alloc_bytes ", "go_info{version=\"go", "process_start_time_seconds "} {
+		if !strings.Contains(output, name) {
+			t.Errorf("expected output to contain %q", name)
+		}
+	}
+}
+
+func TestRegistrationPanics(t *testing.T) {
+	tests := []struct {
+		name     string
+		register func(r *Registry)
+	}{
+		{"duplicate name", func(r *Registry) {
+			r.NewCounter("dup", "")
+			r.NewGauge("dup", "")
+		}},
+		{"invalid metric name", func(r *Registry) { r.NewCounter("bad-name", "") }},
+		{"invalid label name", func(r *Registry) { r.NewCounter("ok", "", "bad-label") }},
+		{"reserved histogram label", func(r *Registry) { r.NewHistogram("h", "", nil, "le") }},
+		{"wrong label count", func(r *Registry) { r.NewCounter("c", "", "a").Inc() }},
+		{"negative counter", func(r *Registry) { r.NewCounter("n", "").Add(-1) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected panic")
+				}
+			}()
+			tt.register(NewRegistry())
+		})
+	}
+}
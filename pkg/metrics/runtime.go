@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"bufio"
+	"runtime"
+	"time"
+)
+
+// processStart approximates the process start time as package initialisation
+var processStart = time.Now()
+
+// RegisterRuntimeMetrics registers Go runtime and process statistics, read
+// once per scrape
+func (r *Registry) RegisterRuntimeMetrics() {
+	r.register(&runtimeCollector{})
+}
+
+type runtimeCollector struct{}
+
+var runtimeFamilies = []family{
+	{name: "go_info", help: "Information about the Go environment.", typ: "gauge", labels: []string{"version"}},
+	{name: "go_goroutines", help: "Number of goroutines that currently exist.", typ: "gauge"},
+	{name: "go_threads", help: "Number of OS threads created.", typ: "gauge"},
+	{name: "go_gc_cycles_total", help: "Number of completed GC cycles.", typ: "counter"},
+	{name: "go_gc_pause_seconds_total", help: "Total time spent in GC stop-the-world pauses.", typ: "counter"},
+	{name: "go_memstats_alloc_bytes", help: "Number of bytes allocated and still in use.", typ: "gauge"},
+	{name: "go_memstats_heap_inuse_bytes", help: "Number of heap bytes that are in use.", typ: "gauge"},
+	{name: "go_memstats_heap_objects", help: "Number of allocated heap objects.", typ: "gauge"},
+	{name: "go_memstats_sys_bytes", help: "Number of bytes obtained from the system.", typ: "gauge"},
+	{name: "process_start_time_seconds", help: "Start time of the process since unix epoch in seconds.", typ: "gauge"},
+}
+
+func (c *runtimeCollector) names() []string {
+	names := make([]string, len(runtimeFamilies))
+	for i, f := range runtimeFamilies {
+		names[i] = f.name
+	}
+	return names
+}
+
+func (c *runtimeCollector) write(w *bufio.Writer) {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	threads, _ := runtime.ThreadCreateProfile(nil)
+
+	values := []float64{
+		1,
+		float64(runtime.NumGoroutine()),
+		float64(threads),
+		float64(ms.NumGC),
+		float64(ms.PauseTotalNs) / 1e9,
+		float64(ms.Alloc),
+		float64(ms.HeapInuse),
+		float64(ms.HeapObjects),
+		float64(ms.Sys),
+		float64(processStart.UnixNano()) / 1e9,
+	}
+
+	for i, f := range runtimeFamilies {
+		f.writeHeader(w)
+		var labelValues []string
+		if f.name == "go_info" {
+			labelValues = []string{runtime.Version()}
+		}
+		f.writeSample(w, "", labelValues, "", "", values[i])
+	}
+}
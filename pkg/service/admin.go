@@ -0,0 +1,69 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+)
+
+// WithAdminPort serves the operational endpoints (health, version, metrics,
+// pprof, log level and feature flags) on a separate plain HTTP port, leaving
+// the main port for application routes only. Zero serves health, version and
+// metrics on the main port, without pprof, the log level or the feature
+// flags, which reveal targeted user IDs.
+func WithAdminPort(port int) Option {
+	return func(s *Service) {
+		s.AdminPort = port
+	}
+}
+
+// setupAdmin creates the admin server when an admin port is configured
+func (s *Service) setupAdmin() {
+	if s.AdminPort == 0 {
+		s.adminMux = s.mux
+		return
+	}
+
+	s.adminMux = http.NewServeMux()
+	s.adminServer = &http.Server{
+		Addr:              fmt.Sprintf(":%d", s.AdminPort),
+		Handler:           s.adminHandler(s.routes(s.adminMux)),
+		ReadHeaderTimeout: s.ReadHeaderTimeout,
+		IdleTimeout:       s.IdleTimeout,
+		MaxHeaderBytes:    s.MaxHeaderBytes,
+		// No write timeout, so CPU profiles and traces can run for as long as
+		// they were requested for
+		ErrorLog: slog.NewLogLogger(s.Log.Handler(), slog.LevelWarn),
+	}
+
+	s.adminMux.HandleFunc("/debug/pprof/", pprof.Index)
+	s.adminMux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	s.adminMux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	s.adminMux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	s.adminMux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	s.adminMux.HandleFunc("GET /_loglevel", s.handleGetLogLevel)
+	s.adminMux.HandleFunc("PUT /_loglevel", s.handlePutLogLevel)
+
+	s.adminMux.HandleFunc("GET /_flags", s.handleFlags)
+}
+
+// adminHandler wraps the admin routes in panic recovery only, so operational
+// requests don't show up in the service's request metrics and traces
+func (s *Service) adminHandler(h http.Handler) http.Handler {
+	h = Recover()(h)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r.WithContext(s.requestContext(r)))
+	})
+}
+
+// AdminHandler returns the handler serving the operational endpoints. It is
+// the same as Handler when no admin port is configured.
+func (s *Service) AdminHandler() http.Handler {
+	if s.adminServer == nil {
+		return s.server.Handler
+	}
+	return s.adminServer.Handler
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAdminPort(t *testing.T) {
+	svc, _ := newTestService(t, WithAdminPort(freePort(t)))
+	svc.HandleFunc("GET /orders", func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		name           string
+		handler        http.Handler
+		path           string
+		expectedStatus int
+	}{
+		{"application route on main", svc.Handler(), "/orders", http.StatusOK},
+		{"root on main", svc.Handler(), "/", http.StatusOK},
+		{"ready not on main", svc.Handler(), "/_ready", http.StatusNotFound},
+		{"metrics not on main", svc.Handler(), "/_metrics", http.StatusNotFound},
+		{"pprof not on main", svc.Handler(), "/debug/pprof/", http.StatusNotFound},
+		{"application route not on admin", svc.AdminHandler(), "/orders", http.StatusNotFound},
+		{"ready on admin", svc.AdminHandler(), "/_ready", http.StatusOK},
+		{"live on admin", svc.AdminHandler(), "/_live", http.StatusOK},
+		{"version on admin", svc.AdminHandler(), "/_version", http.StatusOK},
+		{"metrics on admin", svc.AdminHandler(), "/_metrics", http.StatusOK},
+		{"pprof on admin", svc.AdminHandler(), "/debug/pprof/", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+		})
+	}
+}
+
+func TestAdminPortDisabled(t *testing.T) {
+	svc, _ := newTestService(t)
+
+	rec := httptest.NewRecorder()
+	svc.AdminHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/_ready", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected /_ready on the main port, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	svc.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected pprof not to be served on the main port, got %d", rec.Code)
+	}
+}
+
+func TestRunWithAdminPort(t *testing.T) {
+	svc, _ := newTestService(t, WithPort(freePort(t)), WithAdminPort(freePort(t)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- svc.Run(ctx)
+	}()
+
+	get := func(port int, path string) (int, error) {
+		resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d%s", port, path))
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+
+	var status int
+	var err error
+	for range 100 {
+		if status, err = get(svc.AdminPort, "/_live"); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if status != http.StatusOK {
+		t.Fatalf("expected admin port to serve /_live, got %d %v", status, err)
+	}
+	if status, err := get(svc.Port, "/"); err != nil || status != http.StatusOK {
+		t.Fatalf("expected main port to serve /, got %d %v", status, err)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run returned unexpected error: %v", err)
+	}
+
+	if _, err := get(svc.AdminPort, "/_live"); err == nil {
+		t.Error("expected admin listener to be closed after shutdown")
+	}
+	if _, err := get(svc.Port, "/"); err == nil {
+		t.Error("expected main listener to be closed after shutdown")
+	}
+}
+
+func TestAdminPortMustDiffer(t *testing.T) {
+	if _, err := NewWithName("test", WithPort(8000), WithAdminPort(8000)); err == nil {
+		t.Error("expected error when admin port equals service port")
+	}
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/z0mbix/go-microservices-monorepo/pkg/config"
+	"github.com/z0mbix/go-microservices-monorepo/pkg/featureflag"
+	"github.com/z0mbix/go-microservices-monorepo/pkg/version"
+)
+
+// FromConfig creates the named service from cfg and the feature flags in
+// cfg.FeatureFlagsFile, followed by opts, and makes its logger the default.
+// While the service runs, cfg is watched and the service reloaded with each
+// new configuration.
+func FromConfig(name string, cfg *config.Config, opts ...Option) (*Service, error) {
+	flags, err := featureflag.Load(cfg.FeatureFlagsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := NewWithName(name, append(configOptions(cfg, flags), opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	slog.SetDefault(s.Log)
+
+	s.config = cfg
+	cfg.OnChange(func(_, next *config.Config) {
+		flags, err := featureflag.Load(next.FeatureFlagsFile)
+		if err != nil {
+			s.Log.Error("failed to load feature flags, keeping the current ones", "error", err)
+			flags = s.FeatureFlags
+		}
+		if err := s.Reload(configOptions(next, flags)...); err != nil {
+			s.Log.Error("failed to apply reloaded configuration", "error", err)
+		}
+	})
+	return s, nil
+}
+
+// configOptions configures the service from cfg and the feature flags, both
+// when it starts and when the configuration is reloaded
+func configOptions(cfg *config.Config, flags *featureflag.Set) []Option {
+	return []Option{
+		WithEnvironment(cfg.Environment),
+		WithPort(cfg.Port),
+		WithAdminPort(cfg.AdminPort),
+		WithFeatures(cfg.Features...),
+		WithFeatureFlags(flags),
+		WithLogLevel(cfg.LogLevel),
+		WithLogFormat(cfg.LogFormat),
+		WithLogOutput(cfg.LogOutput),
+		WithBuildInfo(version.Get()),
+		WithReadHeaderTimeout(cfg.HTTP.ReadHeaderTimeout),
+		WithReadTimeout(cfg.HTTP.ReadTimeout),
+		WithWriteTimeout(cfg.HTTP.WriteTimeout),
+		WithIdleTimeout(cfg.HTTP.IdleTimeout),
+		WithMaxHeaderBytes(cfg.HTTP.MaxHeaderBytes),
+		WithMaxConnections(cfg.HTTP.MaxConnections),
+		WithTLS(cfg.TLS.CertFile, cfg.TLS.KeyFile),
+		WithClientCA(cfg.TLS.ClientCAFile),
+	}
+}
+
+// watchConfig reloads the configuration of a service created by FromConfig
+// until ctx is cancelled
+func (s *Service) watchConfig(ctx context.Context) {
+	if s.config == nil {
+		return
+	}
+	s.config.Watch(ctx, func(err error) {
+		s.Log.Error("failed to reload configuration, keeping the current one", "error", err)
+	})
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"github.com/z0mbix/go-microservices-monorepo/pkg/config"
+)
+
+func TestFromConfig(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, file, []byte("log_level: warn\nfeatures: [exports]\n"))
+	cfg, err := config.NewWithArgs([]string{"-config-file", file}, config.WithDefaultPort(8001))
+	if err != nil {
+		t.Fatalf("failed to create config: %v", err)
+	}
+
+	svc, err := FromConfig("billing", cfg, WithVersion("1.2.3"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if svc.Port != 8001 || svc.LogLevel != "warn" || !svc.FeatureEnabled("exports") {
+		t.Errorf("expected the service to be configured from cfg, got port %d, level %q", svc.Port, svc.LogLevel)
+	}
+	if svc.Version != "1.2.3" {
+		t.Errorf("expected opts to be applied after cfg, got version %q", svc.Version)
+	}
+	if slog.Default() != svc.Log {
+		t.Error("expected the service's logger to be the default")
+	}
+
+	writeFile(t, file, []byte("log_level: error\n"))
+	if err := cfg.Reload(); err != nil {
+		t.Fatalf("failed to reload config: %v", err)
+	}
+	if svc.LogLevel != "error" || svc.FeatureEnabled("exports") {
+		t.Errorf("expected the service to be reloaded, got level %q", svc.LogLevel)
+	}
+
+	flagsFile := filepath.Join(t.TempDir(), "flags.yaml")
+	writeFile(t, flagsFile, []byte("checkout: {rollout: 1}\n"))
+	cfg, err = config.NewWithArgs([]string{"-feature-flags-file", flagsFile})
+	if err != nil {
+		t.Fatalf("failed to create config: %v", err)
+	}
+	if _, err := FromConfig("billing", cfg); err == nil {
+		t.Error("expected an error for invalid feature flags")
+	}
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/z0mbix/go-microservices-monorepo/pkg/featureflag"
+	"github.com/z0mbix/go-microservices-monorepo/pkg/logger"
+)
+
+type contextKey int
+
+const (
+	requestIDKey contextKey = iota
+	routeKey
+	tenantKey
+)
+
+// requestContext makes the service logger, the feature flags, the matched
+// route and the tenant available to handlers and middleware. The route and
+// tenant are added to every record logged with the request context.
+func (s *Service) requestContext(r *http.Request) context.Context {
+	route := new(string)
+	ctx := logger.WithContext(r.Context(), s.Log)
+	ctx = featureflag.NewContext(ctx, s.flags.Load())
+	ctx = featureflag.WithAttributes(ctx, s.flagAttributes())
+	ctx = context.WithValue(ctx, routeKey, route)
+	ctx = logger.ContextWithAttrs(ctx, slog.Any("route", routeValue{route}))
+
+	if tenant := r.Header.Get(TenantHeader); validID(tenant) {
+		ctx = context.WithValue(ctx, tenantKey, tenant)
+		ctx = logger.ContextWithAttrs(ctx, slog.String("tenant", tenant))
+	}
+
+	return ctx
+}
+
+// RequestIDFromContext returns the request ID set by the RequestID middleware
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// TenantFromContext returns the tenant sent in the X-Tenant-ID header
+func TenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey).(string)
+	return tenant
+}
+
+// routeFromContext returns the mux pattern which matched the request, once
+// the request has been routed
+func routeFromContext(ctx context.Context) string {
+	if route, ok := ctx.Value(routeKey).(*string); ok {
+		return *route
+	}
+	return ""
+}
+
+// setRoute records the mux pattern which matched the request, so middleware
+// wrapping the mux can see it
+func setRoute(ctx context.Context, pattern string) {
+	if route, ok := ctx.Value(routeKey).(*string); ok {
+		*route = pattern
+	}
+}
+
+// routeValue logs the matched route, which is only known once the request
+// has been routed. It is omitted until then.
+type routeValue struct {
+	route *string
+}
+
+func (v routeValue) LogValue() slog.Value {
+	if *v.route == "" {
+		return slog.GroupValue()
+	}
+	return slog.StringValue(*v.route)
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"net/http"
+
+	"github.com/z0mbix/go-microservices-monorepo/pkg/featureflag"
+)
+
+// WithFeatures enables the named feature flags, see FeatureEnabled
+func WithFeatures(names ...string) Option {
+	return func(s *Service) {
+		s.Features = names
+	}
+}
+
+// WithFeatureFlags sets the feature flags, typically loaded from a file with
+// featureflag.Load. Flags named by WithFeatures are on whatever their
+// definition in set.
+func WithFeatureFlags(set *featureflag.Set) Option {
+	return func(s *Service) {
+		s.FeatureFlags = set
+	}
+}
+
+// FeatureEnabled reports whether the named feature flag is on for the
+// service's environment and version. Handlers evaluating flags for a user
+// should use featureflag.Enabled with the request context instead. Flags can
+// be changed while the service runs with Reload.
+func (s *Service) FeatureEnabled(name string) bool {
+	return s.flags.Load().Enabled(name, s.flagAttributes())
+}
+
+// setFlags combines the feature flags with those enabled by name
+func (s *Service) setFlags() {
+	enabled := make([]featureflag.Flag, 0, len(s.Features))
+	for _, name := range s.Features {
+		enabled = append(enabled, featureflag.Flag{Name: name, Enabled: true})
+	}
+	s.flags.Store(s.FeatureFlags.With(enabled...))
+}
+
+func (s *Service) flagAttributes() featureflag.Attributes {
+	return featureflag.Attributes{Environment: s.Environment, Version: s.Version}
+}
+
+// flagState is a flag as reported by /_flags
+type flagState struct {
+	featureflag.Flag
+	State string `json:"state"`
+}
+
+// handleFlags reports the feature flags and their state for the service's
+// environment and version. With a user_id query parameter, the state is
+// whether each flag is on for that user.
+func (s *Service) handleFlags(w http.ResponseWriter, r *http.Request) {
+	attrs := s.flagAttributes()
+	attrs.UserID = r.URL.Query().Get("user_id")
+
+	flags := s.flags.Load().Flags()
+	states := make([]flagState, 0, len(flags))
+	for _, f := range flags {
+		state := f.State(attrs)
+		if attrs.UserID != "" {
+			state = featureflag.StateOff
+			if f.Evaluate(attrs) {
+				state = featureflag.StateOn
+			}
+		}
+		states = append(states, flagState{Flag: f, State: state})
+	}
+
+	_ = WriteJSON(w, http.StatusOK, map[string]any{"flags": states})
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/z0mbix/go-microservices-monorepo/pkg/featureflag"
+)
+
+func TestFeatureFlags(t *testing.T) {
+	none := 0.0
+	flags := featureflag.New(
+		featureflag.Flag{Name: "checkout_v2", Enabled: true, Rollout: &none, Users: []string{"u-1"}},
+		featureflag.Flag{Name: "new_invoices", Enabled: true, Environments: []string{"production"}},
+		featureflag.Flag{Name: "refunds", Enabled: true, Versions: []string{"1.4.*"}},
+		featureflag.Flag{Name: "exports"},
+	)
+	svc, _ := newTestService(t,
+		WithEnvironment("staging"),
+		WithVersion("1.4.2"),
+		WithFeatureFlags(flags),
+		WithFeatures("exports"),
+		WithAdminPort(freePort(t)),
+	)
+
+	if !svc.FeatureEnabled("refunds") || svc.FeatureEnabled("new_invoices") {
+		t.Error("expected flags to be evaluated for the service's environment and version")
+	}
+	if !svc.FeatureEnabled("exports") {
+		t.Error("expected WithFeatures to enable the flag")
+	}
+
+	svc.HandleFunc("GET /checkout", func(w http.ResponseWriter, r *http.Request) {
+		ctx := featureflag.WithAttributes(r.Context(), featureflag.Attributes{UserID: r.URL.Query().Get("user")})
+		fmt.Fprint(w, featureflag.Enabled(ctx, "checkout_v2"))
+	})
+	for user, want := range map[string]string{"u-1": "true", "u-2": "false"} {
+		rec := httptest.NewRecorder()
+		svc.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/checkout?user="+user, nil))
+		if body, _ := io.ReadAll(rec.Body); string(body) != want {
+			t.Errorf("expected checkout_v2 to be %s for %s, got %s", want, user, body)
+		}
+	}
+
+	states := func(path string) map[string]string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		svc.AdminHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+		var report struct {
+			Flags []struct {
+				Name  string `json:"name"`
+				State string `json:"state"`
+			} `json:"flags"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		got := make(map[string]string)
+		for _, f := range report.Flags {
+			got[f.Name] = f.State
+		}
+		return got
+	}
+
+	// Flags and the users they target are only served on the admin port, so
+	// not at all without one
+	withoutAdmin, _ := newTestService(t, WithFeatureFlags(flags))
+	for _, h := range []http.Handler{svc.Handler(), withoutAdmin.Handler()} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/_flags", nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("expected /_flags to be unavailable on the public port, got status %d", rec.Code)
+		}
+	}
+
+	expected := map[string]string{"checkout_v2": "partial", "exports": "on", "new_invoices": "off", "refunds": "on"}
+	if got := states("/_flags"); fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("expected flag states %v, got %v", expected, got)
+	}
+	if got := states("/_flags?user_id=u-1"); got["checkout_v2"] != "on" {
+		t.Errorf("expected checkout_v2 to be on for u-1, got %v", got)
+	}
+
+	t.Run("reload", func(t *testing.T) {
+		err := svc.Reload(WithFeatureFlags(flags.With(featureflag.Flag{Name: "refunds"})), WithFeatures())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if svc.FeatureEnabled("refunds") || svc.FeatureEnabled("exports") {
+			t.Error("expected the reloaded flags to be off")
+		}
+		if got := states("/_flags"); got["refunds"] != "off" || got["exports"] != "off" {
+			t.Errorf("expected /_flags to show the reloaded flags, got %v", got)
+		}
+	})
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultReadinessTimeout  = 2 * time.Second
+	defaultReadinessCacheTTL = time.Second
+
+	StatusOK          = "ok"
+	StatusDegraded    = "degraded"
+	StatusUnavailable = "unavailable"
+	StatusDraining    = "draining"
+	StatusFailing     = "failing"
+)
+
+// HealthChecker reports whether a dependency of the service is usable
+type HealthChecker interface {
+	CheckHealth(ctx context.Context) error
+}
+
+// HealthCheckFunc adapts a function to the HealthChecker interface
+type HealthCheckFunc func(ctx context.Context) error
+
+func (f HealthCheckFunc) CheckHealth(ctx context.Context) error {
+	return f(ctx)
+}
+
+// CheckOption configures a readiness check
+type CheckOption func(*readinessCheck)
+
+// NonCritical marks a readiness check as informational: a failure is reported
+// as degraded but the service stays ready
+func NonCritical() CheckOption {
+	return func(c *readinessCheck) {
+		c.critical = false
+	}
+}
+
+// WithReadinessCheck adds a check which must pass for /_ready to succeed
+func WithReadinessCheck(name string, check HealthChecker, opts ...CheckOption) Option {
+	return func(s *Service) {
+		c := &readinessCheck{name: name, checker: check, critical: true}
+		for _, opt := range opts {
+			opt(c)
+		}
+		s.readiness.checks = append(s.readiness.checks, c)
+	}
+}
+
+// WithReadinessTimeout sets how long readiness checks may run before they are
+// reported as failing
+func WithReadinessTimeout(d time.Duration) Option {
+	return func(s *Service) {
+		s.readiness.timeout = d
+	}
+}
+
+// WithReadinessCacheTTL sets how long readiness results are reused, so
+// frequent probes don't overload dependencies
+func WithReadinessCacheTTL(d time.Duration) Option {
+	return func(s *Service) {
+		s.readiness.cacheTTL = d
+	}
+}
+
+// HealthReport is the JSON body returned by /_ready
+type HealthReport struct {
+	Service string                 `json:"service"`
+	Status  string                 `json:"status"`
+	Checks  map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// CheckResult is the outcome of a single readiness check
+type CheckResult struct {
+	Status    string    `json:"status"`
+	Critical  bool      `json:"critical"`
+	LatencyMS float64   `json:"latency_ms"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+type readinessCheck struct {
+	name     string
+	checker  HealthChecker
+	critical bool
+}
+
+type readiness struct {
+	checks   []*readinessCheck
+	timeout  time.Duration
+	cacheTTL time.Duration
+
+	mu        sync.Mutex
+	results   map[string]CheckResult
+	checkedAt time.Time
+}
+
+// run returns the check results, running the checks concurrently if the
+// cached results have expired. The checks only inherit the values of ctx, so
+// a probe giving up can't fail the results cached for the others.
+func (r *readiness) run(ctx context.Context) map[string]CheckResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.results != nil && time.Since(r.checkedAt) < r.cacheTTL {
+		return r.results
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.timeout)
+	defer cancel()
+
+	results := make(map[string]CheckResult, len(r.checks))
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, c := range r.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := c.run(ctx)
+			mu.Lock()
+			results[c.name] = result
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	r.results = results
+	r.checkedAt = time.Now()
+
+	return results
+}
+
+func (c *readinessCheck) run(ctx context.Context) CheckResult {
+	start := time.Now()
+	errCh := make(chan error, 1)
+	go func() {
+		defer func() {
+			if v := recover(); v != nil {
+				errCh <- fmt.Errorf("panic: %v", v)
+			}
+		}()
+		errCh <- c.checker.CheckHealth(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out: %w", ctx.Err())
+	}
+
+	result := CheckResult{
+		Status:    StatusOK,
+		Critical:  c.critical,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+		CheckedAt: start,
+	}
+	if err != nil {
+		result.Status = StatusFailing
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+// readinessReport runs the readiness checks and summarises them
+func (s *Service) readinessReport(ctx context.Context) HealthReport {
+	report := HealthReport{
+		Service: s.Name,
+		Status:  StatusOK,
+		Checks:  s.readiness.run(ctx),
+	}
+
+	for _, result := range report.Checks {
+		if result.Status == StatusOK {
+			continue
+		}
+		if result.Critical {
+			report.Status = StatusUnavailable
+			break
+		}
+		report.Status = StatusDegraded
+	}
+
+	if s.draining.Load() {
+		report.Status = StatusDraining
+	}
+
+	return report
+}
+
+func (s *Service) handleReady(w http.ResponseWriter, r *http.Request) {
+	report := s.readinessReport(r.Context())
+
+	status := http.StatusOK
+	if report.Status == StatusUnavailable || report.Status == StatusDraining {
+		status = http.StatusServiceUnavailable
+	}
+
+	_ = WriteJSON(w, status, report)
+}
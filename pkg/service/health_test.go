@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func getReady(t *testing.T, svc *Service) (int, HealthReport) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	svc.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/_ready", nil))
+
+	var report HealthReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("failed to parse readiness report: %v\nBody: %s", err, rec.Body.String())
+	}
+	return rec.Code, report
+}
+
+func TestReadinessChecks(t *testing.T) {
+	passing := HealthCheckFunc(func(ctx context.Context) error { return nil })
+	failing := HealthCheckFunc(func(ctx context.Context) error { return errors.New("connection refused") })
+	slow := HealthCheckFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	tests := []struct {
+		name           string
+		opts           []Option
+		expectedStatus int
+		expectedReport string
+		expectedChecks map[string]string
+	}{
+		{
+			name:           "no checks is ready",
+			expectedStatus: http.StatusOK,
+			expectedReport: StatusOK,
+		},
+		{
+			name: "all checks passing",
+			opts: []Option{
+				WithReadinessCheck("db", passing),
+				WithReadinessCheck("cache", passing),
+			},
+			expectedStatus: http.StatusOK,
+			expectedReport: StatusOK,
+			expectedChecks: map[string]string{"db": StatusOK, "cache": StatusOK},
+		},
+		{
+			name: "critical check failing",
+			opts: []Option{
+				WithReadinessCheck("db", failing),
+				WithReadinessCheck("cache", passing),
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedReport: StatusUnavailable,
+			expectedChecks: map[string]string{"db": StatusFailing, "cache": StatusOK},
+		},
+		{
+			name: "non-critical check failing",
+			opts: []Option{
+				WithReadinessCheck("db", passing),
+				WithReadinessCheck("cache", failing, NonCritical()),
+			},
+			expectedStatus: http.StatusOK,
+			expectedReport: StatusDegraded,
+			expectedChecks: map[string]string{"db": StatusOK, "cache": StatusFailing},
+		},
+		{
+			name: "check exceeding timeout",
+			opts: []Option{
+				WithReadinessTimeout(10 * time.Millisecond),
+				WithReadinessCheck("db", slow),
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedReport: StatusUnavailable,
+			expectedChecks: map[string]string{"db": StatusFailing},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, _ := newTestService(t, tt.opts...)
+
+			status, report := getReady(t, svc)
+			if status != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, status)
+			}
+			if report.Status != tt.expectedReport {
+				t.Errorf("expected report status %q, got %q", tt.expectedReport, report.Status)
+			}
+			if len(report.Checks) != len(tt.expectedChecks) {
+				t.Errorf("expected %d checks, got %d", len(tt.expectedChecks), len(report.Checks))
+			}
+			for name, expected := range tt.expectedChecks {
+				result, ok := report.Checks[name]
+				if !ok {
+					t.Errorf("expected check %q in report", name)
+					continue
+				}
+				if result.Status != expected {
+					t.Errorf("check %q: expected status %q, got %q", name, expected, result.Status)
+				}
+				if result.Status == StatusFailing && result.Error == "" {
+					t.Errorf("check %q: expected error message", name)
+				}
+			}
+		})
+	}
+}
+
+func TestReadinessChecksRunConcurrently(t *testing.T) {
+	// Each check waits until both have started, so they only pass if run in parallel
+	var started atomic.Int32
+	check := HealthCheckFunc(func(ctx context.Context) error {
+		started.Add(1)
+		for started.Load() < 2 {
+			select {
+			case <-ctx.Done():
+				return errors.New("checks did not run concurrently")
+			case <-time.After(time.Millisecond):
+			}
+		}
+		return nil
+	})
+
+	svc, _ := newTestService(t,
+		WithReadinessTimeout(time.Second),
+		WithReadinessCheck("a", check),
+		WithReadinessCheck("b", check),
+	)
+
+	if status, report := getReady(t, svc); status != http.StatusOK {
+		t.Errorf("expected status %d, got %d: %+v", http.StatusOK, status, report)
+	}
+}
+
+func TestReadinessCache(t *testing.T) {
+	var calls atomic.Int32
+	check := HealthCheckFunc(func(ctx context.Context) error {
+		calls.Add(1)
+		return nil
+	})
+
+	svc, _ := newTestService(t,
+		WithReadinessCacheTTL(time.Hour),
+		WithReadinessCheck("db", check),
+	)
+
+	getReady(t, svc)
+	getReady(t, svc)
+	if n := calls.Load(); n != 1 {
+		t.Errorf("expected check to run once while cached, ran %d times", n)
+	}
+
+	svc.readiness.checkedAt = time.Time{}
+	getReady(t, svc)
+	if n := calls.Load(); n != 2 {
+		t.Errorf("expected check to run again after cache expiry, ran %d times", n)
+	}
+}
+
+func TestReadinessIgnoresProbeCancellation(t *testing.T) {
+	check := HealthCheckFunc(func(ctx context.Context) error {
+		return ctx.Err()
+	})
+	svc, _ := newTestService(t,
+		WithReadinessCacheTTL(time.Hour),
+		WithReadinessCheck("db", check),
+	)
+
+	// A probe which gave up must not fail the cached results
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodGet, "/_ready", nil).WithContext(ctx)
+	svc.Handler().ServeHTTP(httptest.NewRecorder(), req)
+
+	if code, report := getReady(t, svc); code != http.StatusOK || report.Checks["db"].Status != StatusOK {
+		t.Errorf("expected the check to pass, got %d %+v", code, report)
+	}
+}
+
+func TestLiveIgnoresReadiness(t *testing.T) {
+	failing := HealthCheckFunc(func(ctx context.Context) error { return errors.New("down") })
+	svc, _ := newTestService(t, WithReadinessCheck("db", failing))
+	svc.draining.Store(true)
+
+	rec := httptest.NewRecorder()
+	svc.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/_live", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected /_live to return %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	if status, report := getReady(t, svc); status != http.StatusServiceUnavailable || report.Status != StatusDraining {
+		t.Errorf("expected draining readiness, got %d %q", status, report.Status)
+	}
+}
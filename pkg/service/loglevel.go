@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/z0mbix/go-microservices-monorepo/pkg/logger"
+)
+
+// WithLogLevelTTL sets how long a runtime log level change lasts before the
+// configured level is restored. Zero keeps changes until the next one.
+func WithLogLevelTTL(d time.Duration) Option {
+	return func(s *Service) {
+		s.level.ttl = d
+	}
+}
+
+// LogLevelStatus is the JSON body of the /_loglevel endpoint
+type LogLevelStatus struct {
+	Level      string     `json:"level"`
+	Configured string     `json:"configured"`
+	RevertAt   *time.Time `json:"revert_at,omitempty"`
+}
+
+// logLevelChange is the JSON body accepted by PUT /_loglevel
+type logLevelChange struct {
+	Level string `json:"level"`
+	TTL   string `json:"ttl"`
+}
+
+// logLevel is the level of the service logger, which can be changed at
+// runtime and optionally reverted to the configured level after a TTL
+type logLevel struct {
+	v   slog.LevelVar
+	ttl time.Duration
+
+	mu         sync.Mutex
+	configured slog.Level
+	timer      *time.Timer
+	revertAt   time.Time
+}
+
+// configure sets the level the service was started with
+func (l *logLevel) configure(level slog.Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.configured = level
+	l.v.Set(level)
+}
+
+// set changes the level, reverting to the configured level after ttl
+func (l *logLevel) set(level slog.Level, ttl time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.timer != nil {
+		l.timer.Stop()
+		l.timer = nil
+	}
+	l.revertAt = time.Time{}
+	l.v.Set(level)
+
+	if ttl > 0 && level != l.configured {
+		l.revertAt = time.Now().Add(ttl)
+		l.timer = time.AfterFunc(ttl, l.revert)
+	}
+}
+
+func (l *logLevel) revert() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.timer = nil
+	l.revertAt = time.Time{}
+	l.v.Set(l.configured)
+}
+
+func (l *logLevel) status() LogLevelStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	status := LogLevelStatus{
+		Level:      levelName(l.v.Level()),
+		Configured: levelName(l.configured),
+	}
+	if !l.revertAt.IsZero() {
+		revertAt := l.revertAt
+		status.RevertAt = &revertAt
+	}
+	return status
+}
+
+// step moves the level by n steps of verbosity, negative being more verbose,
+// staying between debug and error
+func (l *logLevel) step(n int) slog.Level {
+	level := l.v.Level() + slog.Level(4*n)
+	level = min(max(level, slog.LevelDebug), slog.LevelError)
+	l.set(level, l.ttl)
+	return level
+}
+
+func levelName(level slog.Level) string {
+	return strings.ToLower(level.String())
+}
+
+func (s *Service) handleGetLogLevel(w http.ResponseWriter, r *http.Request) {
+	_ = WriteJSON(w, http.StatusOK, s.level.status())
+}
+
+func (s *Service) handlePutLogLevel(w http.ResponseWriter, r *http.Request) {
+	var change logLevelChange
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024)).Decode(&change); err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	level, err := logger.ParseLevel(change.Level)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ttl := s.level.ttl
+	if change.TTL != "" {
+		ttl, err = time.ParseDuration(change.TTL)
+		if err != nil || ttl < 0 {
+			WriteError(w, http.StatusBadRequest, "invalid ttl: "+change.TTL)
+			return
+		}
+	}
+
+	s.level.set(level, ttl)
+	s.Log.WarnContext(r.Context(), "log level changed",
+		"level", levelName(level),
+		"ttl", ttl,
+		"remote_addr", r.RemoteAddr,
+	)
+
+	_ = WriteJSON(w, http.StatusOK, s.level.status())
+}
+
+// watchLogLevelSignals raises or lowers the log level on SIGUSR1/SIGUSR2
+// until ctx is cancelled
+func (s *Service) watchLogLevelSignals(ctx context.Context) {
+	if len(logLevelSignals) == 0 {
+		return
+	}
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, logLevelSignals...)
+
+	go func() {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case sig := <-ch:
+				level := s.level.step(logLevelSignalStep(sig))
+				s.Log.Warn("log level changed", "level", levelName(level), "signal", sig.String(), "ttl", s.level.ttl)
+			}
+		}
+	}()
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package service
+
+import "os"
+
+// Log level signals are only supported on unix
+var logLevelSignals []os.Signal
+
+func logLevelSignalStep(os.Signal) int {
+	return 0
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func doLogLevel(t *testing.T, svc *Service, method, body string) (int, LogLevelStatus) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	svc.AdminHandler().ServeHTTP(rec, httptest.NewRequest(method, "/_loglevel", strings.NewReader(body)))
+
+	var status LogLevelStatus
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+			t.Fatalf("failed to parse response: %v\nBody: %s", err, rec.Body.String())
+		}
+	}
+	return rec.Code, status
+}
+
+func TestLogLevelEndpoint(t *testing.T) {
+	svc, err := NewWithName("test", WithAdminPort(freePort(t)), WithLogLevel("warn"))
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+
+	code, status := doLogLevel(t, svc, http.MethodGet, "")
+	if code != http.StatusOK || status.Level != "warn" || status.Configured != "warn" {
+		t.Fatalf("unexpected initial status %d %+v", code, status)
+	}
+
+	code, status = doLogLevel(t, svc, http.MethodPut, `{"level":"debug"}`)
+	if code != http.StatusOK || status.Level != "debug" || status.RevertAt != nil {
+		t.Fatalf("unexpected status after change %d %+v", code, status)
+	}
+	if !svc.Log.Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("expected service logger to log debug messages after change")
+	}
+
+	invalid := []string{`{"level":"trace"}`, `not json`, `{"level":"debug","ttl":"soon"}`, `{"level":"debug","ttl":"-1m"}`}
+	for _, body := range invalid {
+		if code, _ := doLogLevel(t, svc, http.MethodPut, body); code != http.StatusBadRequest {
+			t.Errorf("expected %d for body %s, got %d", http.StatusBadRequest, body, code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	svc.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/_loglevel", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected /_loglevel not to be served on the main port, got %d", rec.Code)
+	}
+}
+
+func TestLogLevelTTL(t *testing.T) {
+	svc, err := NewWithName("test", WithAdminPort(freePort(t)))
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+
+	code, status := doLogLevel(t, svc, http.MethodPut, `{"level":"debug","ttl":"20ms"}`)
+	if code != http.StatusOK || status.RevertAt == nil {
+		t.Fatalf("expected revert time in status, got %d %+v", code, status)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for svc.level.v.Level() != slog.LevelInfo {
+		if time.Now().After(deadline) {
+			t.Fatal("expected log level to revert to the configured level")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if _, status := doLogLevel(t, svc, http.MethodGet, ""); status.RevertAt != nil {
+		t.Errorf("expected no revert time after reverting, got %v", status.RevertAt)
+	}
+}
+
+func TestLogLevelStep(t *testing.T) {
+	svc, err := NewWithName("test", WithLogLevelTTL(time.Hour))
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+
+	steps := []struct {
+		n        int
+		expected slog.Level
+	}{
+		{-1, slog.LevelDebug},
+		{-1, slog.LevelDebug},
+		{1, slog.LevelInfo},
+		{1, slog.LevelWarn},
+		{1, slog.LevelError},
+		{1, slog.LevelError},
+	}
+	for _, step := range steps {
+		if level := svc.level.step(step.n); level != step.expected {
+			t.Errorf("step(%d) = %v, expected %v", step.n, level, step.expected)
+		}
+	}
+
+	if status := svc.level.status(); status.RevertAt == nil {
+		t.Error("expected signal driven change to use the default TTL")
+	}
+}
+
+func TestLogLevelSignals(t *testing.T) {
+	if len(logLevelSignals) == 0 {
+		t.Skip("log level signals are not supported on this platform")
+	}
+
+	svc, _ := newTestService(t, WithPort(freePort(t)))
+	runService(t, svc)
+
+	process, _ := os.FindProcess(os.Getpid())
+	if err := process.Signal(logLevelSignals[0]); err != nil {
+		t.Fatalf("failed to send signal: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for svc.level.v.Level() != slog.LevelDebug {
+		if time.Now().After(deadline) {
+			t.Fatal("expected log level to become debug after signal")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,18 @@
+//go:build unix
+
+package service
+
+import (
+	"os"
+	"syscall"
+)
+
+// SIGUSR1 makes the logger more verbose, SIGUSR2 less verbose
+var logLevelSignals = []os.Signal{syscall.SIGUSR1, syscall.SIGUSR2}
+
+func logLevelSignalStep(sig os.Signal) int {
+	if sig == syscall.SIGUSR1 {
+		return -1
+	}
+	return 1
+}
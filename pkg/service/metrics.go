@@ -0,0 +1,87 @@
+package service
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/z0mbix/go-microservices-monorepo/pkg/metrics"
+)
+
+// WithMetricsRegistry sets the registry the service records its metrics in and
+// serves at /_metrics. Go runtime metrics are only registered automatically
+// on the default registry.
+func WithMetricsRegistry(registry *metrics.Registry) Option {
+	return func(s *Service) {
+		s.Metrics = registry
+	}
+}
+
+// httpMetrics are the built-in metrics recorded for every request
+type httpMetrics struct {
+	requests *metrics.Counter
+	duration *metrics.Histogram
+}
+
+func (s *Service) registerMetrics() {
+	if s.Metrics == nil {
+		s.Metrics = metrics.NewRegistry()
+		s.Metrics.RegisterRuntimeMetrics()
+	}
+
+	info := s.Metrics.NewGauge("service_info", "Information about the running service.", "name", "version", "environment")
+	info.Set(1, s.Name, s.Version, s.Environment)
+
+	s.httpMetrics = httpMetrics{
+		requests: s.Metrics.NewCounter("http_requests_total",
+			"Total number of HTTP requests handled.", "route", "method", "status"),
+		duration: s.Metrics.NewHistogram("http_request_duration_seconds",
+			"Duration of HTTP requests in seconds.", metrics.DefaultBuckets, "route", "method", "status"),
+	}
+}
+
+// instrument records the request count and latency for every request, labelled
+// by the matched route rather than the path to keep cardinality bounded
+func (s *Service) instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
+
+		defer func() {
+			route := routeLabel(routeFromContext(r.Context()))
+			method := methodLabel(r.Method)
+			status := strconv.Itoa(sw.Status())
+
+			s.httpMetrics.requests.Inc(route, method, status)
+			s.httpMetrics.duration.Observe(time.Since(start).Seconds(), route, method, status)
+		}()
+
+		next.ServeHTTP(sw, r)
+	})
+}
+
+func (s *Service) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", metrics.ContentType)
+	_, _ = s.Metrics.WriteTo(w)
+}
+
+// routeLabel strips the method from a mux pattern, as it is a separate label
+func routeLabel(pattern string) string {
+	if pattern == "" {
+		return "unmatched"
+	}
+	if _, path, found := strings.Cut(pattern, " "); found {
+		return strings.TrimSpace(path)
+	}
+	return pattern
+}
+
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "OTHER"
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/z0mbix/go-microservices-monorepo/pkg/metrics"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	svc, _ := newTestService(t, WithVersion("1.2.3"), WithEnvironment("test"))
+	svc.HandleFunc("GET /orders/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	svc.HandleFunc("GET /panic", func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+
+	for _, path := range []string{"/orders/1", "/orders/2", "/panic", "/missing"} {
+		svc.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	rec := httptest.NewRecorder()
+	svc.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/_metrics", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != metrics.ContentType {
+		t.Errorf("expected content type %q, got %q", metrics.ContentType, ct)
+	}
+
+	output := rec.Body.String()
+	expected := []string{
+		`service_info{name="test",version="1.2.3",environment="test"} 1`,
+		`http_requests_total{route="/orders/{id}",method="GET",status="200"} 2`,
+		`http_requests_total{route="/panic",method="GET",status="500"} 1`,
+		`http_requests_total{route="unmatched",method="GET",status="404"} 1`,
+		`http_request_duration_seconds_count{route="/orders/{id}",method="GET",status="200"} 2`,
+		`go_goroutines `,
+	}
+	for _, line := range expected {
+		if !strings.Contains(output, line) {
+			t.Errorf("expected metrics output to contain %q\n%s", line, output)
+		}
+	}
+}
+
+func TestCustomMetrics(t *testing.T) {
+	registry := metrics.NewRegistry()
+	svc, _ := newTestService(t, WithMetricsRegistry(registry))
+
+	orders := svc.Metrics.NewCounter("orders_created_total", "Orders created.")
+	orders.Inc()
+
+	rec := httptest.NewRecorder()
+	svc.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/_metrics", nil))
+
+	if !strings.Contains(rec.Body.String(), "orders_created_total 1\n") {
+		t.Errorf("expected custom metric in output:\n%s", rec.Body.String())
+	}
+	if svc.Metrics != registry {
+		t.Error("expected service to use the provided registry")
+	}
+}
+
+func TestMethodLabel(t *testing.T) {
+	if got := methodLabel("BREW"); got != "OTHER" {
+		t.Errorf("expected unknown method to be labelled OTHER, got %q", got)
+	}
+	if got := methodLabel(http.MethodPost); got != http.MethodPost {
+		t.Errorf("expected %q, got %q", http.MethodPost, got)
+	}
+}
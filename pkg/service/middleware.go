@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/z0mbix/go-microservices-monorepo/pkg/logger"
+)
+
+const (
+	// RequestIDHeader is the header used to propagate request IDs
+	RequestIDHeader = "X-Request-ID"
+	// TenantHeader identifies the tenant a request is made on behalf of
+	TenantHeader = "X-Tenant-ID"
+
+	maxIDLength = 128
+)
+
+// Middleware wraps an http.Handler with additional behaviour
+type Middleware func(http.Handler) http.Handler
+
+// WithMiddleware appends middleware to the chain wrapping every request the
+// service handles. The first middleware is the outermost one.
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(s *Service) {
+		s.middleware = append(s.middleware, middleware...)
+	}
+}
+
+// chain wraps h so that the first middleware is the outermost one
+func chain(h http.Handler, middleware ...Middleware) http.Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+	return h
+}
+
+// Recover converts panics in handlers into a 500 JSON response, logging the
+// panic value and stack trace through the service logger. Every Service
+// installs it outside the middleware added with WithMiddleware.
+func Recover() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+
+				logger.FromContext(r.Context()).ErrorContext(r.Context(), "panic serving request",
+					"panic", fmt.Sprint(v),
+					"method", r.Method,
+					"path", r.URL.Path,
+					"request_id", RequestIDFromContext(r.Context()),
+					"stack", string(debug.Stack()),
+				)
+				WriteError(w, http.StatusInternalServerError, "")
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequestID propagates the X-Request-ID header, generating a new ID when the
+// client did not send a usable one. The ID is echoed in the response, added
+// to every record logged with the request context and is available to
+// handlers through RequestIDFromContext. Every Service installs it, so panics
+// and the middleware added with WithMiddleware see the ID.
+func RequestID() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if !validID(id) {
+				id = newRequestID()
+				r.Header.Set(RequestIDHeader, id)
+			}
+
+			w.Header().Set(RequestIDHeader, id)
+			ctx := context.WithValue(r.Context(), requestIDKey, id)
+			ctx = logger.ContextWithAttrs(ctx, slog.String("request_id", id))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// AccessLog logs every request with its route, status, duration and the
+// number of bytes written
+func AccessLog() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			sw := &statusWriter{ResponseWriter: w}
+
+			next.ServeHTTP(sw, r)
+
+			logger.FromContext(r.Context()).InfoContext(r.Context(), "request",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"route", routeFromContext(r.Context()),
+				"status", sw.Status(),
+				"duration", time.Since(start),
+				"bytes", sw.bytes,
+				"request_id", RequestIDFromContext(r.Context()),
+				"remote_addr", r.RemoteAddr,
+			)
+		})
+	}
+}
+
+// MaxBodySize limits request bodies to n bytes. Reading past the limit fails
+// and the handler can respond with 413 Request Entity Too Large.
+func MaxBodySize(n int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > n {
+				WriteError(w, http.StatusRequestEntityTooLarge, "")
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, n)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// validID reports whether a request or tenant ID from a header is safe to log
+// and echo back
+func validID(id string) bool {
+	if id == "" || len(id) > maxIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// statusWriter records the status code and number of bytes written
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Status returns the response status, defaulting to 200 if the handler never
+// wrote anything
+func (w *statusWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
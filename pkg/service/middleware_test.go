@@ -0,0 +1,244 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/z0mbix/go-microservices-monorepo/pkg/logger"
+	"github.com/z0mbix/go-microservices-monorepo/pkg/tracing"
+)
+
+func newTestService(t *testing.T, opts ...Option) (*Service, *bytes.Buffer) {
+	t.Helper()
+	svc, err := NewWithName("test", opts...)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+
+	var buf bytes.Buffer
+	svc.Log = slog.New(slog.NewJSONHandler(&buf, nil))
+	return svc, &buf
+}
+
+func TestWithMiddlewareOrder(t *testing.T) {
+	var order []string
+	record := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	svc, _ := newTestService(t, WithMiddleware(record("first"), record("second")), WithMiddleware(record("third")))
+	svc.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if want := []string{"first", "second", "third"}; !slices.Equal(order, want) {
+		t.Errorf("expected middleware order %v, got %v", want, order)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	svc, buf := newTestService(t)
+	svc.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	rec := httptest.NewRecorder()
+	svc.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected status %d, got %d", http.StatusInternalServerError, rec.Code)
+	}
+	if body := rec.Body.String(); body != "{\"error\":\"Internal Server Error\"}\n" {
+		t.Errorf("unexpected body %q", body)
+	}
+
+	var logData map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &logData); err != nil {
+		t.Fatalf("failed to parse JSON log output: %v\nOutput: %s", err, buf.String())
+	}
+	if logData["panic"] != "boom" {
+		t.Errorf("expected panic value to be logged, got %v", logData["panic"])
+	}
+	if stack, _ := logData["stack"].(string); !strings.Contains(stack, "TestRecover") {
+		t.Errorf("expected stack trace to be logged, got %q", stack)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	// Installed by default
+	svc, buf := newTestService(t)
+
+	var seen string
+	svc.HandleFunc("/id", func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestIDFromContext(r.Context())
+		logger.FromContext(r.Context()).Info("finding id")
+	})
+
+	t.Run("propagates incoming ID", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/id", nil)
+		req.Header.Set(RequestIDHeader, "abc-123")
+		rec := httptest.NewRecorder()
+		svc.Handler().ServeHTTP(rec, req)
+
+		if seen != "abc-123" {
+			t.Errorf("expected handler to see request ID %q, got %q", "abc-123", seen)
+		}
+		if got := rec.Header().Get(RequestIDHeader); got != "abc-123" {
+			t.Errorf("expected response header %q, got %q", "abc-123", got)
+		}
+		if !strings.Contains(buf.String(), `"request_id":"abc-123"`) {
+			t.Errorf("expected the request ID to be logged, got: %s", buf.String())
+		}
+	})
+
+	t.Run("generates ID when missing or invalid", func(t *testing.T) {
+		for _, id := range []string{"", "has space", strings.Repeat("x", 200)} {
+			req := httptest.NewRequest(http.MethodGet, "/id", nil)
+			req.Header.Set(RequestIDHeader, id)
+			rec := httptest.NewRecorder()
+			svc.Handler().ServeHTTP(rec, req)
+
+			if seen == "" || seen == id {
+				t.Errorf("expected generated request ID for %q, got %q", id, seen)
+			}
+			if got := rec.Header().Get(RequestIDHeader); got != seen {
+				t.Errorf("expected response header %q, got %q", seen, got)
+			}
+		}
+	})
+}
+
+func TestAccessLog(t *testing.T) {
+	svc, buf := newTestService(t, WithMiddleware(AccessLog()))
+	svc.HandleFunc("GET /orders/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		io.WriteString(w, "hello")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/orders/42", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	svc.Handler().ServeHTTP(httptest.NewRecorder(), req)
+
+	var logData map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &logData); err != nil {
+		t.Fatalf("failed to parse JSON log output: %v\nOutput: %s", err, buf.String())
+	}
+
+	expected := map[string]any{
+		"msg":        "request",
+		"method":     "GET",
+		"path":       "/orders/42",
+		"route":      "GET /orders/{id}",
+		"status":     float64(http.StatusAccepted),
+		"bytes":      float64(5),
+		"request_id": "req-1",
+	}
+	for key, value := range expected {
+		if logData[key] != value {
+			t.Errorf("attribute %q: expected %v, got %v", key, value, logData[key])
+		}
+	}
+	if _, ok := logData["duration"]; !ok {
+		t.Error("expected duration to be logged")
+	}
+}
+
+func TestRequestLogAttrs(t *testing.T) {
+	svc, buf := newTestService(t)
+	svc.Log = slog.New(tracing.NewLogHandler(svc.Log.Handler()))
+
+	var tenant string
+	svc.HandleFunc("GET /orders/{id}", func(w http.ResponseWriter, r *http.Request) {
+		tenant = TenantFromContext(r.Context())
+		logger.FromContext(r.Context()).Info("loading order")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/orders/42", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	req.Header.Set(TenantHeader, "acme")
+	svc.Handler().ServeHTTP(httptest.NewRecorder(), req)
+
+	if tenant != "acme" {
+		t.Errorf("expected handler to see tenant %q, got %q", "acme", tenant)
+	}
+
+	var logData map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &logData); err != nil {
+		t.Fatalf("failed to parse JSON log output: %v\nOutput: %s", err, buf.String())
+	}
+
+	expected := map[string]any{
+		"msg":        "loading order",
+		"route":      "GET /orders/{id}",
+		"request_id": "req-1",
+		"tenant":     "acme",
+	}
+	for key, value := range expected {
+		if logData[key] != value {
+			t.Errorf("attribute %q: expected %v, got %v", key, value, logData[key])
+		}
+	}
+	for _, key := range []string{"trace_id", "span_id"} {
+		if _, ok := logData[key]; !ok {
+			t.Errorf("expected %s to be logged", key)
+		}
+	}
+
+	t.Run("omits unknown route and invalid tenant", func(t *testing.T) {
+		buf.Reset()
+		mw := func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				logger.FromContext(r.Context()).Info("before routing")
+				next.ServeHTTP(w, r)
+			})
+		}
+		req := httptest.NewRequest(http.MethodGet, "/bad", nil)
+		req.Header.Set(TenantHeader, "has space")
+		svc.handler(mw(svc.routes(svc.mux))).ServeHTTP(httptest.NewRecorder(), req)
+
+		if strings.Contains(buf.String(), "route") || strings.Contains(buf.String(), "tenant") {
+			t.Errorf("expected route and tenant to be omitted: %s", buf.String())
+		}
+	})
+}
+
+func TestMaxBodySize(t *testing.T) {
+	svc, _ := newTestService(t, WithMiddleware(MaxBodySize(4)))
+	svc.HandleFunc("POST /upload", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			WriteError(w, http.StatusRequestEntityTooLarge, "")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name           string
+		body           io.Reader
+		expectedStatus int
+	}{
+		{"within limit", strings.NewReader("abcd"), http.StatusNoContent},
+		{"declared length over limit", strings.NewReader("abcdef"), http.StatusRequestEntityTooLarge},
+		{"streamed body over limit", io.MultiReader(strings.NewReader("abc"), strings.NewReader("def")), http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			svc.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/upload", tt.body))
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+		})
+	}
+}
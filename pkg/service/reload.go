@@ -0,0 +1,142 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/z0mbix/go-microservices-monorepo/pkg/logger"
+)
+
+// Reload applies opts to the running service, typically the same options it
+// was created with built from a reloaded configuration. The log level, read
+// and write timeouts and feature flags change immediately. Other settings,
+// such as the port, only change on restart, so changes to them are logged
+// as warnings and otherwise ignored, as are options which do not set an
+// exported field. Nothing changes if the result is invalid.
+func (s *Service) Reload(opts ...Option) error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	next := s.settings()
+	for _, opt := range opts {
+		opt(next)
+	}
+	if err := next.validate(); err != nil {
+		return err
+	}
+	level, err := logger.ParseLevel(next.LogLevel)
+	if err != nil {
+		return err
+	}
+
+	var changed []any
+	if next.LogLevel != s.LogLevel {
+		s.level.configure(level)
+		changed = append(changed, "level", next.LogLevel)
+		s.LogLevel = next.LogLevel
+	}
+	if next.ReadTimeout != s.ReadTimeout || next.WriteTimeout != s.WriteTimeout {
+		s.timeouts.set(next.ReadTimeout, next.WriteTimeout)
+		changed = append(changed, "read_timeout", next.ReadTimeout, "write_timeout", next.WriteTimeout)
+		s.ReadTimeout, s.WriteTimeout = next.ReadTimeout, next.WriteTimeout
+	}
+	if !slices.Equal(next.Features, s.Features) || !next.FeatureFlags.Equal(s.FeatureFlags) {
+		s.Features, s.FeatureFlags = slices.Clone(next.Features), next.FeatureFlags
+		s.setFlags()
+		changed = append(changed, "features", next.Features, "feature_flags", len(next.FeatureFlags.Flags()))
+	}
+
+	restart := []struct {
+		name     string
+		old, new any
+	}{
+		{"port", s.Port, next.Port},
+		{"admin_port", s.AdminPort, next.AdminPort},
+		{"environment", s.Environment, next.Environment},
+		{"log_format", s.LogFormat, next.LogFormat},
+		{"log_output", s.LogOutput, next.LogOutput},
+		{"read_header_timeout", s.ReadHeaderTimeout, next.ReadHeaderTimeout},
+		{"idle_timeout", s.IdleTimeout, next.IdleTimeout},
+		{"max_header_bytes", s.MaxHeaderBytes, next.MaxHeaderBytes},
+		{"max_connections", s.MaxConnections, next.MaxConnections},
+		{"tls_cert_file", s.TLSCertFile, next.TLSCertFile},
+		{"tls_key_file", s.TLSKeyFile, next.TLSKeyFile},
+		{"tls_client_ca_file", s.TLSClientCAFile, next.TLSClientCAFile},
+	}
+	for _, r := range restart {
+		if r.old != r.new {
+			s.Log.Warn("configuration change requires a restart",
+				"setting", r.name,
+				"current", fmt.Sprint(r.old),
+				"configured", fmt.Sprint(r.new),
+			)
+		}
+	}
+
+	if len(changed) > 0 {
+		s.Log.Info("reloaded configuration", changed...)
+	}
+	return nil
+}
+
+// settings returns a Service holding a copy of the settings of s, for Reload
+// to apply options to
+func (s *Service) settings() *Service {
+	return &Service{
+		AdminPort:         s.AdminPort,
+		BuildInfo:         s.BuildInfo,
+		DrainPeriod:       s.DrainPeriod,
+		Environment:       s.Environment,
+		FeatureFlags:      s.FeatureFlags,
+		Features:          slices.Clone(s.Features),
+		IdleTimeout:       s.IdleTimeout,
+		LogFormat:         s.LogFormat,
+		LogLevel:          s.LogLevel,
+		LogOutput:         s.LogOutput,
+		MaxConnections:    s.MaxConnections,
+		MaxHeaderBytes:    s.MaxHeaderBytes,
+		Name:              s.Name,
+		Port:              s.Port,
+		ReadHeaderTimeout: s.ReadHeaderTimeout,
+		ReadTimeout:       s.ReadTimeout,
+		ShutdownTimeout:   s.ShutdownTimeout,
+		TLSCertFile:       s.TLSCertFile,
+		TLSClientCAFile:   s.TLSClientCAFile,
+		TLSKeyFile:        s.TLSKeyFile,
+		Version:           s.Version,
+		WriteTimeout:      s.WriteTimeout,
+		readiness:         readiness{timeout: s.readiness.timeout, cacheTTL: s.readiness.cacheTTL},
+	}
+}
+
+// timeouts are the read and write timeouts currently in force, which may
+// differ from those the HTTP server was started with after a Reload
+type timeouts struct {
+	read  atomic.Int64
+	write atomic.Int64
+}
+
+func (t *timeouts) set(read, write time.Duration) {
+	t.read.Store(int64(read))
+	t.write.Store(int64(write))
+}
+
+// applyTimeouts replaces the HTTP server's deadlines for the request when the
+// timeouts have been reloaded since it started
+func (s *Service) applyTimeouts(w http.ResponseWriter) {
+	read := time.Duration(s.timeouts.read.Load())
+	write := time.Duration(s.timeouts.write.Load())
+	if read == s.server.ReadTimeout && write == s.server.WriteTimeout {
+		return
+	}
+
+	// Deadlines can't be set on every ResponseWriter, e.g. in tests, which
+	// leaves the server's in place
+	rc := http.NewResponseController(w)
+	now := time.Now()
+	_ = rc.SetReadDeadline(now.Add(read))
+	_ = rc.SetWriteDeadline(now.Add(write))
+}
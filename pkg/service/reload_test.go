@@ -0,0 +1,122 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReload(t *testing.T) {
+	var buf bytes.Buffer
+	svc, err := NewWithName("test",
+		WithLogger(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))),
+		WithPort(8001),
+		WithFeatures("checkout"),
+	)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	if !svc.FeatureEnabled("checkout") || svc.FeatureEnabled("refunds") {
+		t.Error("expected only the checkout feature to be enabled")
+	}
+
+	err = svc.Reload(
+		WithPort(9001),
+		WithLogLevel("debug"),
+		WithReadTimeout(time.Second),
+		WithWriteTimeout(2*time.Second),
+		WithFeatures("refunds"),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !svc.Log.Enabled(context.Background(), slog.LevelDebug) || svc.LogLevel != "debug" {
+		t.Error("expected the log level to be reloaded")
+	}
+	if svc.FeatureEnabled("checkout") || !svc.FeatureEnabled("refunds") {
+		t.Error("expected the feature flags to be reloaded")
+	}
+	if svc.timeouts.read.Load() != int64(time.Second) || svc.timeouts.write.Load() != int64(2*time.Second) {
+		t.Error("expected the timeouts to be reloaded")
+	}
+	if svc.Port != 8001 || svc.server.Addr != ":8001" {
+		t.Errorf("expected the port not to change without a restart, got %d", svc.Port)
+	}
+
+	output := buf.String()
+	for _, want := range []string{
+		`"msg":"configuration change requires a restart","setting":"port","current":"8001","configured":"9001"`,
+		`"msg":"reloaded configuration","level":"debug"`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected logs to contain %s, got: %s", want, output)
+		}
+	}
+
+	t.Run("invalid options are not applied", func(t *testing.T) {
+		buf.Reset()
+		if err := svc.Reload(WithLogLevel("info"), WithReadTimeout(0)); err == nil {
+			t.Error("expected error for an invalid read timeout")
+		}
+		if err := svc.Reload(WithLogLevel("trace")); err == nil {
+			t.Error("expected error for an invalid log level")
+		}
+		if svc.LogLevel != "debug" || svc.ReadTimeout != time.Second {
+			t.Error("expected the settings to be unchanged")
+		}
+		if buf.Len() != 0 {
+			t.Errorf("expected nothing to be logged, got: %s", buf.String())
+		}
+	})
+}
+
+func TestReloadWriteTimeout(t *testing.T) {
+	port := freePort(t)
+	svc, err := NewWithName("test", WithPort(port), WithLogOutput("stderr"), WithLogLevel("error"))
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	svc.HandleFunc("GET /slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		fmt.Fprint(w, "done")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- svc.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	get := func() error {
+		resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/slow", port))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		_, err = io.ReadAll(resp.Body)
+		return err
+	}
+
+	if err := get(); err != nil {
+		t.Fatalf("unexpected error before reloading: %v", err)
+	}
+
+	if err := svc.Reload(WithWriteTimeout(50 * time.Millisecond)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := get(); err == nil {
+		t.Error("expected the reloaded write timeout to cut off the slow response")
+	}
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Group registers routes under a shared path prefix, wrapping each of them in
+// the group's middleware
+type Group struct {
+	svc        *Service
+	prefix     string
+	middleware []Middleware
+}
+
+// Group returns a route group whose patterns are relative to prefix. Patterns
+// use the http.ServeMux syntax, e.g. "GET /{id}"; an empty path registers the
+// prefix itself, so "GET " on the group "/orders" matches only "GET /orders".
+func (s *Service) Group(prefix string, middleware ...Middleware) *Group {
+	return &Group{
+		svc:        s,
+		prefix:     strings.TrimSuffix(prefix, "/"),
+		middleware: middleware,
+	}
+}
+
+// Group returns a nested group which inherits the prefix and middleware of g
+func (g *Group) Group(prefix string, middleware ...Middleware) *Group {
+	return &Group{
+		svc:        g.svc,
+		prefix:     g.prefix + strings.TrimSuffix(prefix, "/"),
+		middleware: append(g.middleware[:len(g.middleware):len(g.middleware)], middleware...),
+	}
+}
+
+// Use appends middleware to the group. It only applies to routes registered
+// after it is called.
+func (g *Group) Use(middleware ...Middleware) {
+	g.middleware = append(g.middleware, middleware...)
+}
+
+// Handle registers handler for pattern relative to the group prefix
+func (g *Group) Handle(pattern string, handler http.Handler) {
+	g.svc.Handle(joinPattern(g.prefix, pattern), chain(handler, g.middleware...))
+}
+
+// HandleFunc registers the handler function for pattern relative to the
+// group prefix
+func (g *Group) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	g.Handle(pattern, http.HandlerFunc(handler))
+}
+
+// joinPattern prefixes the path of a "[METHOD ]/path" pattern
+func joinPattern(prefix, pattern string) string {
+	method, path, found := strings.Cut(pattern, " ")
+	if !found {
+		method, path = "", pattern
+	}
+
+	path = prefix + strings.TrimSpace(path)
+	if path == "" {
+		path = "/"
+	}
+
+	if method == "" {
+		return path
+	}
+	return method + " " + path
+}
+
+// routes serves requests from mux, replacing the mux's plain text 404 and
+// 405 responses with JSON ones
+func (s *Service) routes(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pattern := mux.Handler(r)
+		setRoute(r.Context(), pattern)
+		if pattern == "" {
+			w = &errorInterceptor{ResponseWriter: w}
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// errorInterceptor rewrites the not found and method not allowed responses
+// written by http.ServeMux
+type errorInterceptor struct {
+	http.ResponseWriter
+	intercepted bool
+}
+
+func (w *errorInterceptor) WriteHeader(status int) {
+	if status == http.StatusNotFound || status == http.StatusMethodNotAllowed {
+		w.intercepted = true
+		WriteError(w.ResponseWriter, status, "")
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *errorInterceptor) Write(b []byte) (int, error) {
+	if w.intercepted {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *errorInterceptor) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestJoinPattern(t *testing.T) {
+	tests := []struct {
+		prefix   string
+		pattern  string
+		expected string
+	}{
+		{"/orders", "GET /{id}", "GET /orders/{id}"},
+		{"/orders", "/{id}", "/orders/{id}"},
+		{"/orders", "GET ", "GET /orders"},
+		{"/orders", "", "/orders"},
+		{"/orders", "POST /", "POST /orders/"},
+		{"", "GET /", "GET /"},
+		{"", "", "/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.prefix+" "+tt.pattern, func(t *testing.T) {
+			if got := joinPattern(tt.prefix, tt.pattern); got != tt.expected {
+				t.Errorf("joinPattern(%q, %q) = %q, expected %q", tt.prefix, tt.pattern, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGroup(t *testing.T) {
+	svc, err := NewWithName("order")
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+
+	tag := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("X-Middleware", name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	orders := svc.Group("/orders", tag("orders"))
+	orders.HandleFunc("GET ", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "list")
+	})
+	orders.HandleFunc("GET /{id}", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "order %s", r.PathValue("id"))
+	})
+
+	items := orders.Group("/{id}/items", tag("items"))
+	items.HandleFunc("POST ", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, "item for %s", r.PathValue("id"))
+	})
+
+	tests := []struct {
+		name               string
+		method             string
+		path               string
+		expectedStatus     int
+		expectedBody       string
+		expectedMiddleware []string
+		expectedAllow      string
+	}{
+		{
+			name:               "group prefix route",
+			method:             http.MethodGet,
+			path:               "/orders",
+			expectedStatus:     http.StatusOK,
+			expectedBody:       "list",
+			expectedMiddleware: []string{"orders"},
+		},
+		{
+			name:               "path value in group",
+			method:             http.MethodGet,
+			path:               "/orders/42",
+			expectedStatus:     http.StatusOK,
+			expectedBody:       "order 42",
+			expectedMiddleware: []string{"orders"},
+		},
+		{
+			name:               "nested group inherits middleware in order",
+			method:             http.MethodPost,
+			path:               "/orders/42/items",
+			expectedStatus:     http.StatusCreated,
+			expectedBody:       "item for 42",
+			expectedMiddleware: []string{"orders", "items"},
+		},
+		{
+			name:           "unknown route returns JSON 404",
+			method:         http.MethodGet,
+			path:           "/unknown",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   "{\"error\":\"Not Found\"}\n",
+		},
+		{
+			name:           "wrong method returns JSON 405",
+			method:         http.MethodDelete,
+			path:           "/orders/42",
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedBody:   "{\"error\":\"Method Not Allowed\"}\n",
+			expectedAllow:  "GET, HEAD",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			svc.Handler().ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if body := rec.Body.String(); body != tt.expectedBody {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, body)
+			}
+
+			middleware := rec.Header().Values("X-Middleware")
+			if !slices.Equal(middleware, tt.expectedMiddleware) {
+				t.Errorf("expected middleware %v, got %v", tt.expectedMiddleware, middleware)
+			}
+
+			if tt.expectedAllow != "" {
+				if allow := rec.Header().Get("Allow"); allow != tt.expectedAllow {
+					t.Errorf("expected Allow header %q, got %q", tt.expectedAllow, allow)
+				}
+				if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+					t.Errorf("expected JSON content type, got %q", ct)
+				}
+			}
+		})
+	}
+}
+
+func TestRoutesBesideBuiltins(t *testing.T) {
+	svc, err := NewWithName("order")
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+
+	// None of these may conflict with the built-in routes
+	svc.HandleFunc("GET /{id}", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "order %s", r.PathValue("id"))
+	})
+	svc.HandleFunc("GET /", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "fallback")
+	})
+	svc.HandleFunc("DELETE /{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	svc.HandleFunc("POST /{path...}", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "created %s", r.PathValue("path"))
+	})
+
+	tests := []struct {
+		method         string
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{http.MethodGet, "/", http.StatusOK, "order service"},
+		{http.MethodGet, "/42", http.StatusOK, "order 42"},
+		{http.MethodGet, "/42/items", http.StatusOK, "fallback"},
+		{http.MethodGet, "/_live", http.StatusOK, "order service is alive"},
+		// The server discards the body of HEAD responses, the recorder keeps it
+		{http.MethodHead, "/_live", http.StatusOK, "order service is alive"},
+		{http.MethodGet, "/_version?format=text", http.StatusOK, svc.Version},
+		{http.MethodDelete, "/_ready", http.StatusNoContent, ""},
+		{http.MethodPost, "/_live", http.StatusOK, "created _live"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			svc.Handler().ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if rec.Body.String() != tt.expectedBody {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
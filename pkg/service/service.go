@@ -1,100 +1,590 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
+	"github.com/z0mbix/go-microservices-monorepo/pkg/config"
+	"github.com/z0mbix/go-microservices-monorepo/pkg/featureflag"
 	"github.com/z0mbix/go-microservices-monorepo/pkg/logger"
+	"github.com/z0mbix/go-microservices-monorepo/pkg/metrics"
+	"github.com/z0mbix/go-microservices-monorepo/pkg/tracing"
+	"github.com/z0mbix/go-microservices-monorepo/pkg/version"
 )
 
+const (
+	defaultShutdownTimeout   = 15 * time.Second
+	defaultReadHeaderTimeout = 5 * time.Second
+	defaultReadTimeout       = 30 * time.Second
+	defaultWriteTimeout      = 30 * time.Second
+	defaultIdleTimeout       = 120 * time.Second
+	defaultMaxHeaderBytes    = 1 << 20
+)
+
+// ErrShutdownTimeout is returned by Run when the server or the shutdown hooks
+// did not finish before the shutdown deadline.
+var ErrShutdownTimeout = errors.New("shutdown deadline exceeded")
+
+// ShutdownHook is called when the service stops, after the HTTP server has
+// stopped accepting requests.
+type ShutdownHook func(ctx context.Context) error
+
 type Service struct {
-	Environment string
-	LogLevel    string
-	Log         *slog.Logger
-	Name        string
-	Port        int
-	Version     string
+	AdminPort         int
+	BuildInfo         version.Info
+	DrainPeriod       time.Duration
+	Environment       string
+	FeatureFlags      *featureflag.Set
+	Features          []string
+	IdleTimeout       time.Duration
+	LogFormat         string
+	LogLevel          string
+	LogOutput         string
+	Log               *slog.Logger
+	MaxConnections    int
+	MaxHeaderBytes    int
+	Metrics           *metrics.Registry
+	Name              string
+	Port              int
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	ShutdownTimeout   time.Duration
+	TLSCertFile       string
+	TLSClientCAFile   string
+	TLSKeyFile        string
+	Tracer            *tracing.Tracer
+	Version           string
+	WriteTimeout      time.Duration
+
+	adminMux      *http.ServeMux
+	adminServer   *http.Server
+	certs         *certReloader
+	config        *config.Config
+	draining      atomic.Bool
+	flags         atomic.Pointer[featureflag.Set]
+	httpMetrics   httpMetrics
+	level         logLevel
+	logHandler    slog.Handler
+	logOutput     io.Closer
+	middleware    []Middleware
+	mux           *http.ServeMux
+	readiness     readiness
+	redact        []logger.RedactOption
+	reloadMu      sync.Mutex
+	sample        []logger.SampleOption
+	sampled       bool
+	server        *http.Server
+	shutdownHooks []ShutdownHook
+	timeouts      timeouts
+	traceExporter tracing.Exporter
 }
 
 type Option func(*Service)
 
+// WithDrainPeriod sets how long the service keeps serving requests after
+// /_ready starts failing, giving load balancers time to stop sending traffic
+func WithDrainPeriod(d time.Duration) Option {
+	return func(s *Service) {
+		s.DrainPeriod = d
+	}
+}
+
 func WithEnvironment(env string) Option {
 	return func(s *Service) {
 		s.Environment = env
 	}
 }
 
+// WithIdleTimeout sets how long keep-alive connections may stay idle
+func WithIdleTimeout(d time.Duration) Option {
+	return func(s *Service) {
+		s.IdleTimeout = d
+	}
+}
+
+// WithLogFormat sets the log format: json (the default), text or pretty
+func WithLogFormat(format string) Option {
+	return func(s *Service) {
+		s.LogFormat = format
+	}
+}
+
+// WithLogger makes the service write its logs through the handler of l,
+// ignoring the log format and output. The service log level, redaction and
+// sampling still apply.
+func WithLogger(l *slog.Logger) Option {
+	return func(s *Service) {
+		s.logHandler = l.Handler()
+	}
+}
+
+// WithLogLevel sets the configured log level, which runtime changes through
+// /_loglevel or signals revert to
 func WithLogLevel(level string) Option {
 	return func(s *Service) {
 		s.LogLevel = level
-		if s.Log != nil {
-			newLogger, err := logger.New(level)
-			if err == nil {
-				s.Log = newLogger
-			}
+		if parsed, err := logger.ParseLevel(level); err == nil {
+			s.level.configure(parsed)
 		}
 	}
 }
 
+// WithLogRedaction configures how sensitive values are redacted from the
+// service logs. Redaction is always enabled, using the logger package
+// defaults unless replaced by opts.
+func WithLogRedaction(opts ...logger.RedactOption) Option {
+	return func(s *Service) {
+		s.redact = opts
+	}
+}
+
+// WithLogSampling drops repeated debug and info records, so a hot loop or
+// retry storm cannot flood the logs. See logger.NewSamplingHandler.
+func WithLogSampling(opts ...logger.SampleOption) Option {
+	return func(s *Service) {
+		s.sample = opts
+		s.sampled = true
+	}
+}
+
+// WithLogOutput sets where logs are written: stdout (the default), stderr, a
+// rotating file:// URL or syslog. See logger.OpenOutput for the syntax.
+func WithLogOutput(output string) Option {
+	return func(s *Service) {
+		s.LogOutput = output
+	}
+}
+
+// WithMaxConnections limits the number of concurrent connections, further
+// connections wait to be accepted. Zero means no limit.
+func WithMaxConnections(n int) Option {
+	return func(s *Service) {
+		s.MaxConnections = n
+	}
+}
+
+// WithMaxHeaderBytes limits the size of request headers
+func WithMaxHeaderBytes(n int) Option {
+	return func(s *Service) {
+		s.MaxHeaderBytes = n
+	}
+}
+
 func WithPort(port int) Option {
 	return func(c *Service) {
 		c.Port = port
 	}
 }
 
+// WithReadHeaderTimeout sets how long clients have to send request headers
+func WithReadHeaderTimeout(d time.Duration) Option {
+	return func(s *Service) {
+		s.ReadHeaderTimeout = d
+	}
+}
+
+// WithReadTimeout sets how long clients have to send the whole request
+func WithReadTimeout(d time.Duration) Option {
+	return func(s *Service) {
+		s.ReadTimeout = d
+	}
+}
+
+// WithShutdownTimeout sets the deadline for stopping the HTTP server and
+// running the shutdown hooks once the drain period has passed
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(s *Service) {
+		s.ShutdownTimeout = d
+	}
+}
+
+// WithBuildInfo sets the build metadata reported by /_version, and the
+// version to info's
+func WithBuildInfo(info version.Info) Option {
+	return func(s *Service) {
+		s.BuildInfo = info
+		s.Version = info.Version
+	}
+}
+
 func WithVersion(version string) Option {
 	return func(s *Service) {
 		s.Version = version
 	}
 }
 
+// WithWriteTimeout sets how long handlers have to write the response
+func WithWriteTimeout(d time.Duration) Option {
+	return func(s *Service) {
+		s.WriteTimeout = d
+	}
+}
+
 func NewWithName(name string, opts ...Option) (*Service, error) {
 	svc := &Service{
-		LogLevel: "info",
-		Name:     name,
-		Port:     8000,
+		BuildInfo:         version.Get(),
+		IdleTimeout:       defaultIdleTimeout,
+		LogFormat:         logger.FormatJSON,
+		LogLevel:          "info",
+		LogOutput:         "stdout",
+		MaxHeaderBytes:    defaultMaxHeaderBytes,
+		Name:              name,
+		Port:              8000,
+		ReadHeaderTimeout: defaultReadHeaderTimeout,
+		ReadTimeout:       defaultReadTimeout,
+		ShutdownTimeout:   defaultShutdownTimeout,
+		WriteTimeout:      defaultWriteTimeout,
+		readiness: readiness{
+			timeout:  defaultReadinessTimeout,
+			cacheTTL: defaultReadinessCacheTTL,
+		},
 	}
 
-	var err error
-	svc.Log, err = logger.New(svc.LogLevel)
-	if err != nil {
+	for _, opt := range opts {
+		opt(svc)
+	}
+
+	if err := svc.validate(); err != nil {
+		return nil, err
+	}
+
+	if err := svc.setupLogger(); err != nil {
 		return nil, fmt.Errorf("error initializing logger: %w", err)
 	}
+	svc.setFlags()
+	svc.timeouts.set(svc.ReadTimeout, svc.WriteTimeout)
 
-	for _, opt := range opts {
-		opt(svc)
+	svc.setupTracing()
+	svc.registerMetrics()
+
+	svc.mux = http.NewServeMux()
+	svc.server = &http.Server{
+		Addr:              fmt.Sprintf(":%d", svc.Port),
+		Handler:           svc.handler(svc.routes(svc.mux)),
+		ReadHeaderTimeout: svc.ReadHeaderTimeout,
+		ReadTimeout:       svc.ReadTimeout,
+		WriteTimeout:      svc.WriteTimeout,
+		IdleTimeout:       svc.IdleTimeout,
+		MaxHeaderBytes:    svc.MaxHeaderBytes,
+		ErrorLog:          slog.NewLogLogger(svc.Log.Handler(), slog.LevelWarn),
+	}
+	if err := svc.setupTLS(); err != nil {
+		return nil, fmt.Errorf("error initializing TLS: %w", err)
 	}
+	svc.setupAdmin()
+	svc.registerBuiltinRoutes()
 
 	return svc, nil
 }
 
-func (s *Service) Run() error {
+// setupLogger creates the service logger, writing to the handler of the
+// logger given by WithLogger or else to the configured output, which is
+// closed once the service has stopped
+func (s *Service) setupLogger() error {
+	opts := []logger.Option{
+		logger.WithLevel(s.level.configured.String()),
+		logger.WithLevelVar(&s.level.v),
+		logger.WithRedaction(s.redact...),
+	}
+	if s.sampled {
+		opts = append(opts, logger.WithSampling(s.sample...))
+	}
+
+	if s.logHandler != nil {
+		var err error
+		s.Log, err = logger.NewFromOptions(append(opts, logger.WithHandler(s.logHandler))...)
+		return err
+	}
+
+	output, err := logger.OpenOutput(s.LogOutput)
+	if err != nil {
+		return err
+	}
+
+	s.Log, err = logger.NewFromOptions(append(opts,
+		logger.WithFormat(s.LogFormat),
+		logger.WithWriter(output),
+	)...)
+	if err != nil {
+		output.Close()
+		return err
+	}
+	s.logOutput = output
+	return nil
+}
+
+func (s *Service) validate() error {
+	durations := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"read header timeout", s.ReadHeaderTimeout},
+		{"read timeout", s.ReadTimeout},
+		{"write timeout", s.WriteTimeout},
+		{"idle timeout", s.IdleTimeout},
+		{"shutdown timeout", s.ShutdownTimeout},
+		{"readiness timeout", s.readiness.timeout},
+		{"readiness cache TTL", s.readiness.cacheTTL},
+	}
+	for _, d := range durations {
+		if d.value <= 0 {
+			return fmt.Errorf("invalid %s: must be greater than zero, got %s", d.name, d.value)
+		}
+	}
+
+	if s.DrainPeriod < 0 {
+		return fmt.Errorf("invalid drain period: must not be negative, got %s", s.DrainPeriod)
+	}
+	if s.MaxHeaderBytes <= 0 {
+		return fmt.Errorf("invalid max header bytes: must be greater than zero, got %d", s.MaxHeaderBytes)
+	}
+	if s.MaxConnections < 0 {
+		return fmt.Errorf("invalid max connections: must not be negative, got %d", s.MaxConnections)
+	}
+	if s.AdminPort != 0 && s.AdminPort == s.Port {
+		return fmt.Errorf("invalid admin port: must differ from the service port %d", s.Port)
+	}
+
+	return nil
+}
+
+// handler wraps h in the service middleware, making the service logger, the
+// matched route and the tenant available to it through the request context
+func (s *Service) handler(h http.Handler) http.Handler {
+	h = chain(h, s.middleware...)
+	h = s.peerIdentity(h)
+	h = Recover()(h)
+	h = RequestID()(h)
+	h = s.instrument(h)
+	h = s.trace(h)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.applyTimeouts(w)
+		h.ServeHTTP(w, r.WithContext(s.requestContext(r)))
+	})
+}
+
+func (s *Service) registerBuiltinRoutes() {
+	// Registering the built-in routes for GET only leaves other methods, and
+	// wildcards such as "GET /{id}", free for the service's own routes
+	s.mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s service", s.Name)
+	})
+
+	s.adminMux.HandleFunc("GET /_ready", s.handleReady)
+
+	s.adminMux.HandleFunc("GET /_metrics", s.handleMetrics)
+
+	s.adminMux.HandleFunc("GET /_live", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s service is alive", s.Name)
+	})
+
+	s.adminMux.HandleFunc("GET /_version", s.handleVersion)
+}
+
+// handleVersion reports the build metadata as JSON, or the version alone
+// with ?format=text
+func (s *Service) handleVersion(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("format") == "text" {
+		fmt.Fprintf(w, "%s", s.Version)
+		return
+	}
+
+	info := s.BuildInfo
+	if s.Version != "" {
+		info.Version = s.Version
+	}
+	_ = WriteJSON(w, http.StatusOK, info)
+}
+
+// Handle registers the handler for the given pattern on the service's own
+// mux. Routes should be registered before calling Run.
+func (s *Service) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// HandleFunc registers the handler function for the given pattern on the
+// service's own mux
+func (s *Service) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	s.mux.HandleFunc(pattern, handler)
+}
+
+// Handler returns the service's HTTP handler, including the built-in
+// endpoints unless an admin port is configured, so it can be served by
+// httptest in tests
+func (s *Service) Handler() http.Handler {
+	return s.server.Handler
+}
+
+// OnShutdown registers a hook to run when the service stops. Hooks run in
+// reverse registration order, so resources are released in the opposite
+// order to which they were acquired.
+func (s *Service) OnShutdown(hook ShutdownHook) {
+	s.shutdownHooks = append(s.shutdownHooks, hook)
+}
+
+// Run serves HTTP, and the admin endpoints if an admin port is configured,
+// until ctx is cancelled or the process receives SIGINT or SIGTERM, then
+// drains and shuts down gracefully. If a listener fails, e.g. because its
+// port is in use, Run stops without draining and returns the error, still
+// running the shutdown hooks.
+func (s *Service) Run(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	s.watchLogLevelSignals(ctx)
+	s.watchConfig(ctx)
+
+	listener, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return s.fail(err)
+	}
+	if s.MaxConnections > 0 {
+		listener = newLimitListener(listener, s.MaxConnections)
+	}
+
+	var adminListener net.Listener
+	if s.adminServer != nil {
+		adminListener, err = net.Listen("tcp", s.adminServer.Addr)
+		if err != nil {
+			listener.Close()
+			return s.fail(fmt.Errorf("admin listener: %w", err))
+		}
+	}
+
 	s.Log.Info("starting",
 		"service", s.Name,
 		"port", s.Port,
+		"admin_port", s.AdminPort,
 		"version", s.Version,
 		"environment", s.Environment,
 		"level", s.LogLevel,
+		"max_connections", s.MaxConnections,
+		"tls", s.tlsEnabled(),
+		"client_auth", s.TLSClientCAFile != "",
 	)
 
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		fmt.Fprintf(w, "%s service", s.Name)
-	})
+	errCh := make(chan error, 2)
+	go func() {
+		if s.tlsEnabled() {
+			errCh <- s.server.ServeTLS(listener, "", "")
+			return
+		}
+		errCh <- s.server.Serve(listener)
+	}()
+	if adminListener != nil {
+		go func() {
+			errCh <- s.adminServer.Serve(adminListener)
+		}()
+	}
 
-	http.HandleFunc("/_ready", func(w http.ResponseWriter, r *http.Request) {
-		fmt.Fprintf(w, "%s service is ready", s.Name)
-	})
+	select {
+	case err := <-errCh:
+		// One listener failing stops the other too
+		return s.fail(err)
+	case <-ctx.Done():
+	}
 
-	http.HandleFunc("/_live", func(w http.ResponseWriter, r *http.Request) {
-		fmt.Fprintf(w, "%s service is alive", s.Name)
-	})
+	// Restore default signal behaviour so a second signal terminates immediately
+	stop()
 
-	http.HandleFunc("/_version", func(w http.ResponseWriter, r *http.Request) {
-		fmt.Fprintf(w, "%s", s.Version)
-	})
+	return s.shutdown()
+}
+
+// servers returns the main server and the admin server, if there is one
+func (s *Service) servers() []*http.Server {
+	if s.adminServer == nil {
+		return []*http.Server{s.server}
+	}
+	return []*http.Server{s.server, s.adminServer}
+}
+
+// closeLogOutput closes the output the service opened for its logs, once it
+// has stopped logging
+func (s *Service) closeLogOutput() error {
+	if s.logOutput == nil {
+		return nil
+	}
+	return s.logOutput.Close()
+}
+
+// fail stops the service after it failed to serve, without draining as it
+// can't take requests, running the shutdown hooks so resources are released
+func (s *Service) fail(err error) error {
+	s.Log.Error("failed to serve", "service", s.Name, "error", err)
+	return errors.Join(err, s.stop())
+}
+
+func (s *Service) shutdown() error {
+	s.Log.Info("shutting down",
+		"service", s.Name,
+		"drain_period", s.DrainPeriod,
+		"timeout", s.ShutdownTimeout,
+	)
+
+	s.draining.Store(true)
+	time.Sleep(s.DrainPeriod)
+
+	return s.stop()
+}
+
+// stop shuts the servers down and runs the shutdown hooks
+func (s *Service) stop() (err error) {
+	// The log output is closed last so the final "stopped" line is written
+	defer func() {
+		err = errors.Join(err, s.closeLogOutput())
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.ShutdownTimeout)
+	defer cancel()
+
+	servers := s.servers()
+	serverErrs := make([]error, len(servers))
+	var wg sync.WaitGroup
+	for i, server := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := server.Shutdown(ctx); err != nil {
+				server.Close()
+				if errors.Is(err, context.DeadlineExceeded) {
+					err = fmt.Errorf("%w: http server %s still had active connections after %s", ErrShutdownTimeout, server.Addr, s.ShutdownTimeout)
+				}
+				serverErrs[i] = err
+			}
+		}()
+	}
+	wg.Wait()
+
+	errs := slices.DeleteFunc(serverErrs, func(err error) bool { return err == nil })
+
+	for i := len(s.shutdownHooks) - 1; i >= 0; i-- {
+		if err := s.shutdownHooks[i](ctx); err != nil {
+			errs = append(errs, fmt.Errorf("shutdown hook: %w", err))
+		}
+	}
+
+	err = errors.Join(errs...)
+	if ctx.Err() != nil && !errors.Is(err, ErrShutdownTimeout) {
+		err = errors.Join(fmt.Errorf("%w: shutdown hooks took longer than %s", ErrShutdownTimeout, s.ShutdownTimeout), err)
+	}
+	if err != nil {
+		return err
+	}
 
-	port := fmt.Sprintf(":%d", s.Port)
+	s.Log.Info("stopped", "service", s.Name)
 
-	return http.ListenAndServe(port, nil)
+	return nil
 }
@@ -0,0 +1,424 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/z0mbix/go-microservices-monorepo/pkg/logger"
+	"github.com/z0mbix/go-microservices-monorepo/pkg/version"
+)
+
+func freePort(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find free port: %v", err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func TestRunShutdown(t *testing.T) {
+	svc, err := NewWithName("test", WithPort(freePort(t)))
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+
+	var order []string
+	svc.OnShutdown(func(ctx context.Context) error {
+		order = append(order, "first")
+		return nil
+	})
+	svc.OnShutdown(func(ctx context.Context) error {
+		order = append(order, "second")
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- svc.Run(ctx)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run returned unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after context was cancelled")
+	}
+
+	if want := []string{"second", "first"}; !slices.Equal(order, want) {
+		t.Errorf("expected hooks to run in order %v, got %v", want, order)
+	}
+	if !svc.draining.Load() {
+		t.Error("expected service to be draining after shutdown")
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	svc, err := NewWithName("test", WithShutdownTimeout(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+
+	hookErr := errors.New("pool close failed")
+	svc.OnShutdown(func(ctx context.Context) error {
+		<-ctx.Done()
+		return hookErr
+	})
+
+	err = svc.shutdown()
+	if !errors.Is(err, ErrShutdownTimeout) {
+		t.Errorf("expected ErrShutdownTimeout, got %v", err)
+	}
+	if !errors.Is(err, hookErr) {
+		t.Errorf("expected hook error to be returned, got %v", err)
+	}
+}
+
+func TestRunListenError(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer l.Close()
+
+	taken := l.Addr().(*net.TCPAddr).Port
+
+	for name, opts := range map[string][]Option{
+		"port":       {WithPort(taken)},
+		"admin port": {WithPort(freePort(t)), WithAdminPort(taken)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			svc, err := NewWithName("test", opts...)
+			if err != nil {
+				t.Fatalf("failed to create service: %v", err)
+			}
+			var hookRan bool
+			svc.OnShutdown(func(ctx context.Context) error {
+				hookRan = true
+				return nil
+			})
+
+			if err := svc.Run(context.Background()); err == nil {
+				t.Fatal("expected error when port is already in use")
+			}
+			if !hookRan {
+				t.Error("expected the shutdown hooks to run when the service fails to listen")
+			}
+		})
+	}
+}
+
+func TestHandle(t *testing.T) {
+	// Two services in one process must not share routes
+	order, err := NewWithName("order")
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	billing, err := NewWithName("billing")
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+
+	order.HandleFunc("/orders", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "orders")
+	})
+
+	tests := []struct {
+		name           string
+		svc            *Service
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"order root", order, "/", http.StatusOK, "order service"},
+		{"billing root", billing, "/", http.StatusOK, "billing service"},
+		{"order custom route", order, "/orders", http.StatusOK, "orders"},
+		{"billing does not see order route", billing, "/orders", http.StatusNotFound, "{\"error\":\"Not Found\"}\n"},
+		{"order live", order, "/_live", http.StatusOK, "order service is alive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.svc.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			body, _ := io.ReadAll(rec.Body)
+			if string(body) != tt.expectedBody {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, body)
+			}
+		})
+	}
+}
+
+func TestVersionEndpoint(t *testing.T) {
+	info := version.Info{Version: "1.4.2", Revision: "3f2c1ab", GoVersion: "go1.24.1", OS: "linux", Arch: "amd64"}
+	svc, _ := newTestService(t, WithBuildInfo(info))
+
+	rec := httptest.NewRecorder()
+	svc.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/_version", nil))
+	var got version.Info
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if got != info || rec.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("expected the build info as JSON, got %+v", got)
+	}
+
+	rec = httptest.NewRecorder()
+	svc.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/_version?format=text", nil))
+	if body := rec.Body.String(); body != "1.4.2" {
+		t.Errorf("expected the plain version, got %q", body)
+	}
+
+	t.Run("version option", func(t *testing.T) {
+		svc, _ := newTestService(t, WithBuildInfo(info), WithVersion("test-version"))
+		rec := httptest.NewRecorder()
+		svc.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/_version", nil))
+		if !strings.Contains(rec.Body.String(), `"version":"test-version","revision":"3f2c1ab"`) {
+			t.Errorf("expected the version set by WithVersion, got %s", rec.Body.String())
+		}
+	})
+}
+
+func TestReadyWhileDraining(t *testing.T) {
+	svc, err := NewWithName("test")
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	svc.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/_ready", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status %d before draining, got %d", http.StatusOK, rec.Code)
+	}
+
+	svc.draining.Store(true)
+
+	rec = httptest.NewRecorder()
+	svc.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/_ready", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d while draining, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+}
+
+func TestServerLimits(t *testing.T) {
+	svc, err := NewWithName("test",
+		WithReadHeaderTimeout(time.Second),
+		WithReadTimeout(2*time.Second),
+		WithWriteTimeout(3*time.Second),
+		WithIdleTimeout(4*time.Second),
+		WithMaxHeaderBytes(4096),
+	)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+
+	if svc.server.ReadHeaderTimeout != time.Second ||
+		svc.server.ReadTimeout != 2*time.Second ||
+		svc.server.WriteTimeout != 3*time.Second ||
+		svc.server.IdleTimeout != 4*time.Second ||
+		svc.server.MaxHeaderBytes != 4096 {
+		t.Errorf("server limits not applied: %+v", svc.server)
+	}
+
+	invalid := map[string]Option{
+		"zero read header timeout": WithReadHeaderTimeout(0),
+		"negative write timeout":   WithWriteTimeout(-time.Second),
+		"zero max header bytes":    WithMaxHeaderBytes(0),
+		"negative max connections": WithMaxConnections(-1),
+		"negative drain period":    WithDrainPeriod(-time.Second),
+		"zero readiness timeout":   WithReadinessTimeout(0),
+		"negative readiness TTL":   WithReadinessCacheTTL(-time.Second),
+	}
+	for name, opt := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if _, err := NewWithName("test", opt); err == nil {
+				t.Error("expected error but got nil")
+			}
+		})
+	}
+}
+
+func TestLimitListener(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	l := newLimitListener(inner, 1)
+	defer l.Close()
+
+	accepted := make(chan net.Conn, 2)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- conn
+		}
+	}()
+
+	for range 2 {
+		conn, err := net.Dial("tcp", inner.Addr().String())
+		if err != nil {
+			t.Fatalf("failed to dial: %v", err)
+		}
+		defer conn.Close()
+	}
+
+	first := <-accepted
+	select {
+	case <-accepted:
+		t.Fatal("expected second connection to wait while the first is open")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	first.Close()
+	select {
+	case conn := <-accepted:
+		conn.Close()
+	case <-time.After(time.Second):
+		t.Fatal("expected second connection to be accepted once the first closed")
+	}
+}
+
+func TestLogOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "service.log")
+	svc, err := NewWithName("test",
+		WithLogFormat("text"),
+		WithLogOutput("file://"+path),
+		WithShutdownTimeout(time.Second),
+	)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+
+	svc.Log.Info("written to file")
+	if err := svc.shutdown(); err != nil {
+		t.Fatalf("shutdown returned unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	if !strings.Contains(string(data), "msg=\"written to file\"") {
+		t.Errorf("expected text log line in file, got: %s", data)
+	}
+	if !strings.Contains(string(data), "msg=stopped") {
+		t.Errorf("expected shutdown to be logged before the file is closed, got: %s", data)
+	}
+
+	if _, err := NewWithName("test", WithLogFormat("xml")); err == nil {
+		t.Error("expected invalid log format to return an error")
+	}
+	if _, err := NewWithName("test", WithLogOutput("kafka://broker")); err == nil {
+		t.Error("expected invalid log output to return an error")
+	}
+}
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error {
+	return f()
+}
+
+func TestRunClosesLogOutputOnFailure(t *testing.T) {
+	// The port is taken, so the service fails to listen
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer l.Close()
+
+	svc, _ := newTestService(t, WithPort(l.Addr().(*net.TCPAddr).Port))
+	var closed bool
+	svc.logOutput = closerFunc(func() error {
+		closed = true
+		return nil
+	})
+	if err := svc.Run(context.Background()); err == nil {
+		t.Fatal("expected Run to fail")
+	}
+	if !closed {
+		t.Error("expected the log output to be closed")
+	}
+}
+
+func TestLogRedaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "service.log")
+	svc, err := NewWithName("test",
+		WithLogOutput("file://"+path),
+		WithLogRedaction(logger.RedactKeys("customer")),
+	)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+
+	svc.Log.Info("charged", "customer", "Jane Doe", "card", "4111111111111111")
+	if err := svc.shutdown(); err != nil {
+		t.Fatalf("shutdown returned unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	if strings.Contains(string(data), "Jane Doe") || strings.Contains(string(data), "4111111111111111") {
+		t.Errorf("expected sensitive values to be redacted, got: %s", data)
+	}
+}
+
+func TestWithLogger(t *testing.T) {
+	original := slog.Default()
+
+	var buf bytes.Buffer
+	injected := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	svc, err := NewWithName("test", WithLogger(injected), WithLogLevel("debug"), WithLogOutput("kafka://ignored"))
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	if slog.Default() != original {
+		t.Error("expected creating a service not to replace the default logger")
+	}
+
+	svc.Log.Debug("through injected logger", "password", "hunter2")
+	output := buf.String()
+	if !strings.Contains(output, "through injected logger") {
+		t.Errorf("expected record to reach the injected logger: %s", output)
+	}
+	if strings.Contains(output, "hunter2") {
+		t.Errorf("expected redaction to apply to the injected logger: %s", output)
+	}
+
+	buf.Reset()
+	svc.level.set(slog.LevelInfo, 0)
+	svc.Log.Debug("hidden")
+	if buf.Len() != 0 {
+		t.Errorf("expected the service log level to apply to the injected logger: %s", buf.String())
+	}
+}
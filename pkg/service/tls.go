@@ -0,0 +1,262 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const defaultCertReloadInterval = 5 * time.Second
+
+// WithTLS serves HTTPS using the given certificate and key files, which are
+// reloaded when they change on disk. Empty paths leave TLS disabled.
+func WithTLS(certFile, keyFile string) Option {
+	return func(s *Service) {
+		s.TLSCertFile = certFile
+		s.TLSKeyFile = keyFile
+	}
+}
+
+// WithClientCA requires clients to present a certificate signed by a CA in
+// caFile, which is reloaded when it changes on disk. An empty path leaves
+// client certificates optional and unverified.
+func WithClientCA(caFile string) Option {
+	return func(s *Service) {
+		s.TLSClientCAFile = caFile
+	}
+}
+
+// PeerIdentity describes the verified client certificate of a mutual TLS
+// connection
+type PeerIdentity struct {
+	CommonName     string
+	DNSNames       []string
+	EmailAddresses []string
+	URIs           []string
+	SerialNumber   string
+}
+
+type peerKey struct{}
+
+// PeerFromContext returns the identity of the client certificate verified for
+// the request, if the service requires client certificates
+func PeerFromContext(ctx context.Context) (PeerIdentity, bool) {
+	peer, ok := ctx.Value(peerKey{}).(PeerIdentity)
+	return peer, ok
+}
+
+func (s *Service) tlsEnabled() bool {
+	return s.TLSCertFile != "" || s.TLSKeyFile != ""
+}
+
+// setupTLS loads the certificates, failing early if they are unusable
+func (s *Service) setupTLS() error {
+	if !s.tlsEnabled() {
+		if s.TLSClientCAFile != "" {
+			return errors.New("client CA requires TLS to be enabled")
+		}
+		return nil
+	}
+	if s.TLSCertFile == "" || s.TLSKeyFile == "" {
+		return errors.New("TLS requires both a certificate and a key file")
+	}
+
+	s.certs = &certReloader{
+		certFile: s.TLSCertFile,
+		keyFile:  s.TLSKeyFile,
+		caFile:   s.TLSClientCAFile,
+		interval: defaultCertReloadInterval,
+		onError: func(err error) {
+			s.Log.Error("failed to reload TLS certificates, keeping current ones", "error", err)
+		},
+		onReload: func() {
+			s.Log.Info("reloaded TLS certificates", "cert_file", s.TLSCertFile, "client_ca_file", s.TLSClientCAFile)
+		},
+	}
+	if err := s.certs.load(); err != nil {
+		return err
+	}
+
+	s.server.TLSConfig = s.certs.tlsConfig()
+
+	return nil
+}
+
+// peerIdentity makes the verified client certificate available to handlers
+func (s *Service) peerIdentity(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.TLSClientCAFile == "" || r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cert := r.TLS.PeerCertificates[0]
+		peer := PeerIdentity{
+			CommonName:     cert.Subject.CommonName,
+			DNSNames:       cert.DNSNames,
+			EmailAddresses: cert.EmailAddresses,
+			SerialNumber:   cert.SerialNumber.String(),
+		}
+		for _, uri := range cert.URIs {
+			peer.URIs = append(peer.URIs, uri.String())
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), peerKey{}, peer)))
+	})
+}
+
+// certReloader serves the certificate and client CAs from disk, reloading
+// them at most once per interval if the files have been modified
+type certReloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+	interval time.Duration
+	onError  func(error)
+	onReload func()
+
+	mu          sync.Mutex
+	cert        *tls.Certificate
+	clientCAs   *x509.CertPool
+	modTimes    map[string]time.Time
+	lastChecked time.Time
+}
+
+func (c *certReloader) tlsConfig() *tls.Config {
+	cfg := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: c.getCertificate,
+	}
+
+	// The client CA pool can't be swapped on a shared tls.Config, so chains
+	// are verified against the current pool instead. Unlike
+	// VerifyPeerCertificate, VerifyConnection also runs for resumed sessions,
+	// so they are checked against the current pool too.
+	if c.caFile != "" {
+		cfg.ClientAuth = tls.RequireAnyClientCert
+		cfg.VerifyConnection = c.verifyClientCertificate
+	}
+
+	return cfg
+}
+
+func (c *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.maybeReload()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cert, nil
+}
+
+func (c *certReloader) verifyClientCertificate(cs tls.ConnectionState) error {
+	certs := cs.PeerCertificates
+	if len(certs) == 0 {
+		return errors.New("client certificate required")
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	// Resumed sessions don't ask for the server's certificate, which is what
+	// otherwise picks up a rotated client CA file
+	c.maybeReload()
+
+	c.mu.Lock()
+	roots := c.clientCAs
+	c.mu.Unlock()
+
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return fmt.Errorf("verifying client certificate: %w", err)
+	}
+	return nil
+}
+
+func (c *certReloader) files() []string {
+	files := []string{c.certFile, c.keyFile}
+	if c.caFile != "" {
+		files = append(files, c.caFile)
+	}
+	return files
+}
+
+// load reads the certificate, key and client CAs from disk
+func (c *certReloader) load() error {
+	modTimes := make(map[string]time.Time)
+	for _, file := range c.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return fmt.Errorf("reading TLS file: %w", err)
+		}
+		modTimes[file] = info.ModTime()
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("loading TLS certificate: %w", err)
+	}
+
+	var clientCAs *x509.CertPool
+	if c.caFile != "" {
+		pem, err := os.ReadFile(c.caFile)
+		if err != nil {
+			return fmt.Errorf("reading client CA file: %w", err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in client CA file %s", c.caFile)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cert = &cert
+	c.clientCAs = clientCAs
+	c.modTimes = modTimes
+	c.lastChecked = time.Now()
+
+	return nil
+}
+
+// maybeReload reloads the files if the check interval has passed and any of
+// them has changed. On failure the current certificates are kept.
+func (c *certReloader) maybeReload() {
+	c.mu.Lock()
+	if time.Since(c.lastChecked) < c.interval {
+		c.mu.Unlock()
+		return
+	}
+	c.lastChecked = time.Now()
+
+	changed := false
+	for _, file := range c.files() {
+		info, err := os.Stat(file)
+		if err != nil || !info.ModTime().Equal(c.modTimes[file]) {
+			changed = true
+			break
+		}
+	}
+	c.mu.Unlock()
+
+	if !changed {
+		return
+	}
+
+	if err := c.load(); err != nil {
+		c.onError(err)
+		return
+	}
+	c.onReload()
+}
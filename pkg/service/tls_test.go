@@ -0,0 +1,296 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create CA certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+
+	return &testCA{
+		cert: cert,
+		key:  key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+// issue returns a PEM encoded certificate and key signed by the CA
+func (ca *testCA) issue(t *testing.T, serial int64, cn string, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     []string{cn},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func runService(t *testing.T, svc *Service) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- svc.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	addr := fmt.Sprintf("127.0.0.1:%d", svc.Port)
+	for range 100 {
+		if conn, err := net.Dial("tcp", addr); err == nil {
+			conn.Close()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("service did not start listening on %s", addr)
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	serverCA := newTestCA(t, "server-ca")
+	clientCA := newTestCA(t, "client-ca")
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	caFile := filepath.Join(dir, "ca.crt")
+
+	certPEM, keyPEM := serverCA.issue(t, 10, "billing", x509.ExtKeyUsageServerAuth)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+	writeFile(t, caFile, clientCA.pem)
+
+	svc, _ := newTestService(t,
+		WithPort(freePort(t)),
+		WithTLS(certFile, keyFile),
+		WithClientCA(caFile),
+	)
+	svc.certs.interval = 0
+	svc.HandleFunc("GET /whoami", func(w http.ResponseWriter, r *http.Request) {
+		peer, ok := PeerFromContext(r.Context())
+		if !ok {
+			WriteError(w, http.StatusUnauthorized, "")
+			return
+		}
+		fmt.Fprint(w, peer.CommonName)
+	})
+	runService(t, svc)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(serverCA.cert)
+	url := fmt.Sprintf("https://127.0.0.1:%d/whoami", svc.Port)
+
+	newClient := func(certs ...tls.Certificate) *http.Client {
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:      roots,
+			Certificates: certs,
+		}}}
+	}
+
+	t.Run("verified client certificate", func(t *testing.T) {
+		clientCert, clientKey := clientCA.issue(t, 20, "order", x509.ExtKeyUsageClientAuth)
+		pair, err := tls.X509KeyPair(clientCert, clientKey)
+		if err != nil {
+			t.Fatalf("failed to load client certificate: %v", err)
+		}
+
+		resp, err := newClient(pair).Get(url)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		buf := make([]byte, 64)
+		n, _ := resp.Body.Read(buf)
+		if resp.StatusCode != http.StatusOK || string(buf[:n]) != "order" {
+			t.Errorf("expected peer identity %q, got %d %q", "order", resp.StatusCode, buf[:n])
+		}
+	})
+
+	t.Run("missing client certificate", func(t *testing.T) {
+		if resp, err := newClient().Get(url); err == nil {
+			resp.Body.Close()
+			t.Error("expected request without client certificate to fail")
+		}
+	})
+
+	t.Run("client certificate from another CA", func(t *testing.T) {
+		clientCert, clientKey := serverCA.issue(t, 30, "intruder", x509.ExtKeyUsageClientAuth)
+		pair, _ := tls.X509KeyPair(clientCert, clientKey)
+		if resp, err := newClient(pair).Get(url); err == nil {
+			resp.Body.Close()
+			t.Error("expected request with untrusted client certificate to fail")
+		}
+	})
+
+	t.Run("resumed session after the client CA changes", func(t *testing.T) {
+		clientCert, clientKey := clientCA.issue(t, 40, "order", x509.ExtKeyUsageClientAuth)
+		pair, _ := tls.X509KeyPair(clientCert, clientKey)
+		client := &http.Client{Transport: &http.Transport{
+			DisableKeepAlives: true,
+			TLSClientConfig: &tls.Config{
+				RootCAs:            roots,
+				Certificates:       []tls.Certificate{pair},
+				ClientSessionCache: tls.NewLRUClientSessionCache(1),
+			},
+		}}
+		get := func() (*http.Response, error) {
+			resp, err := client.Get(url)
+			if err == nil {
+				resp.Body.Close()
+			}
+			return resp, err
+		}
+
+		for range 2 {
+			if _, err := get(); err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+		}
+		if resp, _ := get(); !resp.TLS.DidResume {
+			t.Fatal("expected the session to be resumed")
+		}
+
+		writeFile(t, caFile, newTestCA(t, "new-client-ca").pem)
+		future := time.Now().Add(time.Minute)
+		os.Chtimes(caFile, future, future)
+
+		if _, err := get(); err == nil {
+			t.Error("expected a resumed session to be checked against the new client CA")
+		}
+	})
+}
+
+func TestCertificateReload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "ca")
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+
+	certPEM, keyPEM := ca.issue(t, 1, "shipping", x509.ExtKeyUsageServerAuth)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+
+	svc, _ := newTestService(t, WithPort(freePort(t)), WithTLS(certFile, keyFile))
+	svc.certs.interval = 0
+	runService(t, svc)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	servedSerial := func() int64 {
+		conn, err := tls.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", svc.Port), &tls.Config{RootCAs: roots})
+		if err != nil {
+			t.Fatalf("TLS handshake failed: %v", err)
+		}
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0].SerialNumber.Int64()
+	}
+
+	if serial := servedSerial(); serial != 1 {
+		t.Fatalf("expected serial 1, got %d", serial)
+	}
+
+	// Rotate the certificate, making sure the modification time changes
+	certPEM, keyPEM = ca.issue(t, 2, "shipping", x509.ExtKeyUsageServerAuth)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, future, future)
+
+	if serial := servedSerial(); serial != 2 {
+		t.Errorf("expected rotated certificate with serial 2, got %d", serial)
+	}
+
+	// A broken rotation keeps serving the last good certificate
+	writeFile(t, certFile, []byte("not a certificate"))
+	future = future.Add(time.Minute)
+	os.Chtimes(certFile, future, future)
+
+	if serial := servedSerial(); serial != 2 {
+		t.Errorf("expected previous certificate to be kept, got serial %d", serial)
+	}
+}
+
+func TestTLSOptionsValidation(t *testing.T) {
+	tests := map[string][]Option{
+		"certificate without key":  {WithTLS("tls.crt", "")},
+		"client CA without TLS":    {WithClientCA("ca.crt")},
+		"missing certificate file": {WithTLS("/nonexistent/tls.crt", "/nonexistent/tls.key")},
+	}
+	for name, opts := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := NewWithName("test", opts...); err == nil {
+				t.Error("expected error but got nil")
+			}
+		})
+	}
+}
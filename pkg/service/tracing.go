@@ -0,0 +1,60 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/z0mbix/go-microservices-monorepo/pkg/tracing"
+)
+
+// WithTraceExporter sets where the service's spans are sent. Without an
+// exporter, trace context is still propagated and logged but spans are
+// discarded.
+func WithTraceExporter(exporter tracing.Exporter) Option {
+	return func(s *Service) {
+		s.traceExporter = exporter
+	}
+}
+
+func (s *Service) setupTracing() {
+	s.Tracer = tracing.NewTracer(s.Name, s.traceExporter, tracing.WithErrorHandler(func(err error) {
+		s.Log.Warn("failed to export spans", "error", err)
+	}))
+	s.OnShutdown(s.Tracer.Shutdown)
+
+	s.Log = slog.New(tracing.NewLogHandler(s.Log.Handler()))
+}
+
+// trace starts a server span for every request, continuing the trace from
+// the caller's traceparent header if there is one
+func (s *Service) trace(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if sc, ok := tracing.Extract(r.Header); ok {
+			ctx = tracing.ContextWithRemoteSpanContext(ctx, sc)
+		}
+
+		ctx, span := s.Tracer.Start(ctx, r.Method,
+			tracing.WithSpanKind(tracing.SpanKindServer),
+			tracing.WithAttributes(
+				"http.request.method", r.Method,
+				"url.path", r.URL.Path,
+			),
+		)
+		defer span.End()
+
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r.WithContext(ctx))
+
+		route := routeLabel(routeFromContext(ctx))
+		span.SetName(r.Method + " " + route)
+		span.SetAttributes(
+			"http.route", route,
+			"http.response.status_code", sw.Status(),
+		)
+		if sw.Status() >= 500 {
+			span.SetStatus(tracing.StatusError, fmt.Sprintf("responded with %d", sw.Status()))
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/z0mbix/go-microservices-monorepo/pkg/tracing"
+)
+
+func TestTracing(t *testing.T) {
+	exporter := tracing.NewInMemoryExporter()
+	svc, _ := newTestService(t, WithTraceExporter(exporter))
+
+	var buf bytes.Buffer
+	svc.Log = slog.New(tracing.NewLogHandler(slog.NewJSONHandler(&buf, nil)))
+
+	var upstream http.Header
+	billing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstream = r.Header.Clone()
+	}))
+	defer billing.Close()
+
+	svc.HandleFunc("POST /orders/{id}", func(w http.ResponseWriter, r *http.Request) {
+		svc.Log.InfoContext(r.Context(), "creating order")
+
+		// Calls made with client.New send their trace context the same way
+		client := &http.Client{Transport: tracing.NewTransport(svc.Tracer, nil)}
+		req, _ := http.NewRequestWithContext(r.Context(), http.MethodPost, billing.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Errorf("call to billing failed: %v", err)
+			return
+		}
+		resp.Body.Close()
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/orders/1", nil)
+	req.Header.Set(tracing.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	svc.Handler().ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("expected client and server spans, got %d", len(spans))
+	}
+	client, server := spans[0], spans[1]
+
+	if server.Name != "POST /orders/{id}" || server.Kind != tracing.SpanKindServer {
+		t.Errorf("unexpected server span %q kind %v", server.Name, server.Kind)
+	}
+	if server.SpanContext.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected server span to continue the inbound trace, got %s", server.SpanContext.TraceID)
+	}
+	if server.Parent.String() != "00f067aa0ba902b7" {
+		t.Errorf("expected server span parent to be the caller's span, got %s", server.Parent)
+	}
+	if client.Parent != server.SpanContext.SpanID || client.Kind != tracing.SpanKindClient {
+		t.Errorf("expected client span to be a child of the server span")
+	}
+
+	sc, ok := tracing.Extract(upstream)
+	if !ok || sc.SpanID != client.SpanContext.SpanID {
+		t.Errorf("expected billing to receive the client span context, got %v", upstream)
+	}
+
+	var logData map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &logData); err != nil {
+		t.Fatalf("failed to parse JSON log output: %v", err)
+	}
+	if logData["trace_id"] != server.SpanContext.TraceID.String() || logData["span_id"] != server.SpanContext.SpanID.String() {
+		t.Errorf("expected log record to carry the server span IDs, got %v", logData)
+	}
+}
@@ -0,0 +1,400 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Exporter receives spans as they end. ExportSpans is called on the request
+// path, so exporters which send spans over the network should queue them
+// rather than block.
+type Exporter interface {
+	ExportSpans(ctx context.Context, spans []SpanData) error
+	Shutdown(ctx context.Context) error
+}
+
+// InMemoryExporter keeps every exported span, for use in tests
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+func (e *InMemoryExporter) ExportSpans(ctx context.Context, spans []SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func (e *InMemoryExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// Spans returns the spans exported so far, in the order they ended
+func (e *InMemoryExporter) Spans() []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return slices.Clone(e.spans)
+}
+
+// Reset discards all exported spans
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = nil
+}
+
+const (
+	defaultOTLPBatchSize     = 512
+	defaultOTLPQueueSize     = 2048
+	defaultOTLPFlushInterval = 5 * time.Second
+	defaultOTLPTimeout       = 10 * time.Second
+)
+
+var ErrExporterShutdown = errors.New("exporter is shut down")
+
+// OTLPExporter sends spans to an OpenTelemetry collector using OTLP over HTTP
+// with JSON encoding. Spans are queued and sent in batches in the background;
+// spans are dropped when the queue is full.
+type OTLPExporter struct {
+	endpoint      string
+	headers       map[string]string
+	client        *http.Client
+	batchSize     int
+	flushInterval time.Duration
+	onError       func(error)
+
+	queue    chan SpanData
+	flushCh  chan chan struct{}
+	done     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+// OTLPOption configures an OTLPExporter
+type OTLPOption func(*OTLPExporter)
+
+// WithOTLPHeaders sets headers sent with every export request, e.g. for
+// authentication
+func WithOTLPHeaders(headers map[string]string) OTLPOption {
+	return func(e *OTLPExporter) {
+		e.headers = headers
+	}
+}
+
+// WithOTLPClient sets the HTTP client used to send spans
+func WithOTLPClient(client *http.Client) OTLPOption {
+	return func(e *OTLPExporter) {
+		e.client = client
+	}
+}
+
+// WithOTLPBatch sets the maximum number of spans per request and how often
+// queued spans are sent
+func WithOTLPBatch(size int, interval time.Duration) OTLPOption {
+	return func(e *OTLPExporter) {
+		e.batchSize = size
+		e.flushInterval = interval
+	}
+}
+
+// WithOTLPErrorHandler sets the function called when sending spans fails
+func WithOTLPErrorHandler(fn func(error)) OTLPOption {
+	return func(e *OTLPExporter) {
+		e.onError = fn
+	}
+}
+
+// NewOTLPExporter returns an exporter sending spans to the collector at
+// endpoint, e.g. http://localhost:4318. The /v1/traces path is appended
+// unless endpoint already ends with it.
+func NewOTLPExporter(endpoint string, opts ...OTLPOption) *OTLPExporter {
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	if !strings.HasSuffix(endpoint, "/v1/traces") {
+		endpoint += "/v1/traces"
+	}
+
+	e := &OTLPExporter{
+		endpoint:      endpoint,
+		client:        &http.Client{Timeout: defaultOTLPTimeout},
+		batchSize:     defaultOTLPBatchSize,
+		flushInterval: defaultOTLPFlushInterval,
+		onError:       func(error) {},
+		queue:         make(chan SpanData, defaultOTLPQueueSize),
+		flushCh:       make(chan chan struct{}),
+		done:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	go e.loop()
+
+	return e
+}
+
+// ExportSpans queues spans to be sent in the next batch
+func (e *OTLPExporter) ExportSpans(ctx context.Context, spans []SpanData) error {
+	select {
+	case <-e.done:
+		return ErrExporterShutdown
+	default:
+	}
+
+	dropped := 0
+	for _, span := range spans {
+		select {
+		case e.queue <- span:
+		default:
+			dropped++
+		}
+	}
+	if dropped > 0 {
+		return fmt.Errorf("otlp exporter queue full, dropped %d spans", dropped)
+	}
+	return nil
+}
+
+// Flush sends all queued spans
+func (e *OTLPExporter) Flush(ctx context.Context) error {
+	ack := make(chan struct{})
+	select {
+	case e.flushCh <- ack:
+	case <-e.stopped:
+		return ErrExporterShutdown
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-ack:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown sends any queued spans and stops the exporter
+func (e *OTLPExporter) Shutdown(ctx context.Context) error {
+	e.stopOnce.Do(func() {
+		close(e.done)
+	})
+
+	select {
+	case <-e.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (e *OTLPExporter) loop() {
+	defer close(e.stopped)
+
+	ticker := time.NewTicker(e.flushInterval)
+	defer ticker.Stop()
+
+	var batch []SpanData
+	send := func() {
+		for n := len(e.queue); n > 0; n-- {
+			batch = append(batch, <-e.queue)
+		}
+		for len(batch) > 0 {
+			n := min(len(batch), e.batchSize)
+			if err := e.send(batch[:n]); err != nil {
+				e.onError(err)
+			}
+			batch = batch[n:]
+		}
+		batch = nil
+	}
+
+	for {
+		select {
+		case span := <-e.queue:
+			batch = append(batch, span)
+			if len(batch) >= e.batchSize {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		case ack := <-e.flushCh:
+			send()
+			close(ack)
+		case <-e.done:
+			send()
+			return
+		}
+	}
+}
+
+func (e *OTLPExporter) send(spans []SpanData) error {
+	body, err := json.Marshal(otlpRequest(spans))
+	if err != nil {
+		return fmt.Errorf("encoding spans: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating export request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending spans: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("sending spans: collector returned %s", resp.Status)
+	}
+	return nil
+}
+
+// The types below follow the OTLP/JSON encoding of ExportTraceServiceRequest
+
+type otlpTraces struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	TraceState        string         `json:"traceState,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+func otlpRequest(spans []SpanData) otlpTraces {
+	byService := make(map[string][]otlpSpan)
+	var services []string
+	for _, span := range spans {
+		if _, ok := byService[span.ServiceName]; !ok {
+			services = append(services, span.ServiceName)
+		}
+		byService[span.ServiceName] = append(byService[span.ServiceName], otlpSpanFrom(span))
+	}
+
+	traces := otlpTraces{}
+	for _, service := range services {
+		traces.ResourceSpans = append(traces.ResourceSpans, otlpResourceSpans{
+			Resource: otlpResource{
+				Attributes: []otlpKeyValue{otlpAttribute("service.name", service)},
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: "github.com/z0mbix/go-microservices-monorepo/pkg/tracing"},
+				Spans: byService[service],
+			}},
+		})
+	}
+	return traces
+}
+
+func otlpSpanFrom(span SpanData) otlpSpan {
+	s := otlpSpan{
+		TraceID:           span.SpanContext.TraceID.String(),
+		SpanID:            span.SpanContext.SpanID.String(),
+		TraceState:        span.SpanContext.TraceState,
+		Name:              span.Name,
+		Kind:              int(span.Kind),
+		StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+		Status:            otlpStatus{Code: int(span.Status), Message: span.StatusMessage},
+	}
+	if span.Parent.IsValid() {
+		s.ParentSpanID = span.Parent.String()
+	}
+
+	keys := make([]string, 0, len(span.Attributes))
+	for k := range span.Attributes {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		s.Attributes = append(s.Attributes, otlpAttribute(k, span.Attributes[k]))
+	}
+
+	return s
+}
+
+func otlpAttribute(key string, value any) otlpKeyValue {
+	var v otlpValue
+	switch value := value.(type) {
+	case string:
+		v.StringValue = &value
+	case bool:
+		v.BoolValue = &value
+	case int:
+		s := strconv.Itoa(value)
+		v.IntValue = &s
+	case int64:
+		s := strconv.FormatInt(value, 10)
+		v.IntValue = &s
+	case float64:
+		v.DoubleValue = &value
+	default:
+		s := fmt.Sprint(value)
+		v.StringValue = &s
+	}
+	return otlpKeyValue{Key: key, Value: v}
+}
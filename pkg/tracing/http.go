@@ -0,0 +1,50 @@
+package tracing
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Transport is an http.RoundTripper which records a client span for every
+// outbound request and propagates it with W3C trace context headers
+type Transport struct {
+	Tracer *Tracer
+	Base   http.RoundTripper
+}
+
+// NewTransport wraps base, or http.DefaultTransport if base is nil
+func NewTransport(tracer *Tracer, base http.RoundTripper) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{Tracer: tracer, Base: base}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := t.Tracer.Start(req.Context(), "HTTP "+req.Method,
+		WithSpanKind(SpanKindClient),
+		WithAttributes(
+			"http.request.method", req.Method,
+			"server.address", req.URL.Host,
+			"url.full", req.URL.Redacted(),
+		),
+	)
+	defer span.End()
+
+	// RoundTrippers must not modify the caller's request
+	req = req.Clone(ctx)
+	Inject(req.Header, span.SpanContext())
+
+	resp, err := t.Base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	span.SetAttributes("http.response.status_code", resp.StatusCode)
+	if resp.StatusCode >= 500 {
+		span.SetStatus(StatusError, fmt.Sprintf("server responded with %s", resp.Status))
+	}
+
+	return resp, nil
+}
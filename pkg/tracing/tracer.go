@@ -0,0 +1,111 @@
+package tracing
+
+import (
+	"context"
+	"time"
+)
+
+// Tracer starts spans for a service and passes them to an exporter once they
+// end
+type Tracer struct {
+	serviceName string
+	exporter    Exporter
+	onError     func(error)
+}
+
+// TracerOption configures a Tracer
+type TracerOption func(*Tracer)
+
+// WithErrorHandler sets the function called when exporting spans fails
+func WithErrorHandler(fn func(error)) TracerOption {
+	return func(t *Tracer) {
+		t.onError = fn
+	}
+}
+
+// NewTracer returns a tracer for the named service. A nil exporter still
+// creates and propagates spans, but discards them when they end.
+func NewTracer(serviceName string, exporter Exporter, opts ...TracerOption) *Tracer {
+	t := &Tracer{
+		serviceName: serviceName,
+		exporter:    exporter,
+		onError:     func(error) {},
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// SpanOption configures a span when it is started
+type SpanOption func(*Span)
+
+// WithSpanKind sets the kind of the span, which defaults to internal
+func WithSpanKind(kind SpanKind) SpanOption {
+	return func(s *Span) {
+		s.kind = kind
+	}
+}
+
+// WithAttributes sets key/value pairs on the span when it is started
+func WithAttributes(kv ...any) SpanOption {
+	return func(s *Span) {
+		for i := 0; i+1 < len(kv); i += 2 {
+			if key, ok := kv[i].(string); ok {
+				s.attributes[key] = kv[i+1]
+			}
+		}
+	}
+}
+
+// Start starts a span as a child of the span or remote span context in ctx,
+// or as the root of a new trace if there is neither. The returned context
+// carries the new span.
+func (t *Tracer) Start(ctx context.Context, name string, opts ...SpanOption) (context.Context, *Span) {
+	span := &Span{
+		tracer:     t,
+		name:       name,
+		kind:       SpanKindInternal,
+		start:      time.Now(),
+		attributes: make(map[string]any),
+	}
+
+	if parent := SpanContextFromContext(ctx); parent.IsValid() {
+		span.spanContext = SpanContext{
+			TraceID:    parent.TraceID,
+			SpanID:     newSpanID(),
+			Flags:      parent.Flags,
+			TraceState: parent.TraceState,
+		}
+		span.parent = parent.SpanID
+	} else {
+		span.spanContext = SpanContext{
+			TraceID: newTraceID(),
+			SpanID:  newSpanID(),
+			Flags:   flagSampled,
+		}
+	}
+
+	for _, opt := range opts {
+		opt(span)
+	}
+
+	return ContextWithSpan(ctx, span), span
+}
+
+// Shutdown flushes and stops the exporter
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if t.exporter == nil {
+		return nil
+	}
+	return t.exporter.Shutdown(ctx)
+}
+
+func (t *Tracer) export(span SpanData) {
+	if t.exporter == nil {
+		return
+	}
+	if err := t.exporter.ExportSpans(context.Background(), []SpanData{span}); err != nil {
+		t.onError(err)
+	}
+}
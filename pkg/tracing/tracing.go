@@ -0,0 +1,182 @@
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	// TraceparentHeader carries the trace ID, parent span ID and flags
+	TraceparentHeader = "traceparent"
+	// TracestateHeader carries vendor specific trace data
+	TracestateHeader = "tracestate"
+
+	flagSampled byte = 0x01
+
+	maxTracestateLength  = 512
+	maxTracestateMembers = 32
+)
+
+var ErrInvalidTraceparent = errors.New("invalid traceparent")
+
+// TraceID identifies a trace across every service it passes through
+type TraceID [16]byte
+
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+// SpanID identifies a single span within a trace
+type SpanID [8]byte
+
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+// SpanContext is the part of a span which is propagated between services
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Flags      byte
+	TraceState string
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+func (sc SpanContext) IsSampled() bool {
+	return sc.Flags&flagSampled != 0
+}
+
+// Traceparent formats the span context as a W3C traceparent header value
+func (sc SpanContext) Traceparent() string {
+	return fmt.Sprintf("00-%s-%s-%02x", sc.TraceID, sc.SpanID, sc.Flags)
+}
+
+// ParseTraceparent parses a W3C traceparent header value
+func ParseTraceparent(s string) (SpanContext, error) {
+	var sc SpanContext
+
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 {
+		return sc, fmt.Errorf("%w: expected 4 fields, got %d", ErrInvalidTraceparent, len(parts))
+	}
+
+	version, err := decodeHex(parts[0], 1)
+	if err != nil || version[0] == 0xff {
+		return sc, fmt.Errorf("%w: bad version %q", ErrInvalidTraceparent, parts[0])
+	}
+	// Future versions may append fields, but version 00 has exactly four
+	if version[0] == 0 && len(parts) != 4 {
+		return sc, fmt.Errorf("%w: expected 4 fields, got %d", ErrInvalidTraceparent, len(parts))
+	}
+
+	traceID, err := decodeHex(parts[1], len(sc.TraceID))
+	if err != nil {
+		return sc, fmt.Errorf("%w: bad trace ID %q", ErrInvalidTraceparent, parts[1])
+	}
+	spanID, err := decodeHex(parts[2], len(sc.SpanID))
+	if err != nil {
+		return sc, fmt.Errorf("%w: bad parent ID %q", ErrInvalidTraceparent, parts[2])
+	}
+	flags, err := decodeHex(parts[3], 1)
+	if err != nil {
+		return sc, fmt.Errorf("%w: bad flags %q", ErrInvalidTraceparent, parts[3])
+	}
+
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+	sc.Flags = flags[0]
+
+	if !sc.IsValid() {
+		return SpanContext{}, fmt.Errorf("%w: all zero trace or parent ID", ErrInvalidTraceparent)
+	}
+
+	return sc, nil
+}
+
+// decodeHex decodes a fixed length lowercase hex string
+func decodeHex(s string, n int) ([]byte, error) {
+	if len(s) != n*2 || strings.ToLower(s) != s {
+		return nil, ErrInvalidTraceparent
+	}
+	return hex.DecodeString(s)
+}
+
+// Extract reads the span context from W3C trace context headers. It returns
+// false if there is no valid traceparent header.
+func Extract(h http.Header) (SpanContext, bool) {
+	sc, err := ParseTraceparent(h.Get(TraceparentHeader))
+	if err != nil {
+		return SpanContext{}, false
+	}
+	sc.TraceState = validTracestate(strings.Join(h.Values(TracestateHeader), ","))
+	return sc, true
+}
+
+// Inject writes the span context as W3C trace context headers
+func Inject(h http.Header, sc SpanContext) {
+	if !sc.IsValid() {
+		return
+	}
+	h.Set(TraceparentHeader, sc.Traceparent())
+	if sc.TraceState != "" {
+		h.Set(TracestateHeader, sc.TraceState)
+	} else {
+		h.Del(TracestateHeader)
+	}
+}
+
+// validTracestate returns the tracestate with empty members removed, or an
+// empty string if it exceeds the limits set by the specification
+func validTracestate(s string) string {
+	var members []string
+	for _, member := range strings.Split(s, ",") {
+		member = strings.TrimSpace(member)
+		if member == "" {
+			continue
+		}
+		if key, _, found := strings.Cut(member, "="); !found || key == "" {
+			return ""
+		}
+		members = append(members, member)
+	}
+
+	if len(members) > maxTracestateMembers {
+		return ""
+	}
+	tracestate := strings.Join(members, ",")
+	if len(tracestate) > maxTracestateLength {
+		return ""
+	}
+	return tracestate
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+	return id
+}
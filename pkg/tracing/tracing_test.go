@@ -0,0 +1,275 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		expectError bool
+		sampled     bool
+	}{
+		{"valid sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, true},
+		{"valid not sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", false, false},
+		{"future version with extra field", "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false, true},
+		{"empty", "", true, false},
+		{"version ff", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, false},
+		{"version 00 with extra field", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", true, false},
+		{"uppercase hex", "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", true, false},
+		{"short trace ID", "00-4bf92f3577b34da6-00f067aa0ba902b7-01", true, false},
+		{"zero trace ID", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", true, false},
+		{"zero span ID", "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", true, false},
+		{"non-hex flags", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-zz", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, err := ParseTraceparent(tt.value)
+			if tt.expectError {
+				if !errors.Is(err, ErrInvalidTraceparent) {
+					t.Errorf("expected ErrInvalidTraceparent, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+				t.Errorf("unexpected trace ID %s", sc.TraceID)
+			}
+			if sc.SpanID.String() != "00f067aa0ba902b7" {
+				t.Errorf("unexpected span ID %s", sc.SpanID)
+			}
+			if sc.IsSampled() != tt.sampled {
+				t.Errorf("expected sampled %v, got %v", tt.sampled, sc.IsSampled())
+			}
+		})
+	}
+}
+
+func TestInjectExtract(t *testing.T) {
+	h := http.Header{}
+	h.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.Add(TracestateHeader, "congo=t61rcWkgMzE")
+	h.Add(TracestateHeader, " rojo=00f067aa0ba902b7,")
+
+	sc, ok := Extract(h)
+	if !ok {
+		t.Fatal("expected span context to be extracted")
+	}
+	if sc.TraceState != "congo=t61rcWkgMzE,rojo=00f067aa0ba902b7" {
+		t.Errorf("unexpected tracestate %q", sc.TraceState)
+	}
+
+	out := http.Header{}
+	Inject(out, sc)
+	if got := out.Get(TraceparentHeader); got != h.Get(TraceparentHeader) {
+		t.Errorf("expected traceparent %q, got %q", h.Get(TraceparentHeader), got)
+	}
+	if got := out.Get(TracestateHeader); got != sc.TraceState {
+		t.Errorf("expected tracestate %q, got %q", sc.TraceState, got)
+	}
+
+	if _, ok := Extract(http.Header{}); ok {
+		t.Error("expected no span context without headers")
+	}
+}
+
+func TestTracerStart(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	tracer := NewTracer("order", exporter)
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	_, child := tracer.Start(ctx, "child", WithSpanKind(SpanKindClient), WithAttributes("key", "value"))
+	child.End()
+	child.End()
+	root.End()
+
+	spans := exporter.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+
+	childData, rootData := spans[0], spans[1]
+	if childData.SpanContext.TraceID != rootData.SpanContext.TraceID {
+		t.Error("expected child to share the root trace ID")
+	}
+	if childData.Parent != rootData.SpanContext.SpanID {
+		t.Error("expected child parent to be the root span")
+	}
+	if rootData.Parent.IsValid() {
+		t.Error("expected root span to have no parent")
+	}
+	if childData.Kind != SpanKindClient || childData.Attributes["key"] != "value" {
+		t.Errorf("unexpected child span %+v", childData)
+	}
+	if childData.ServiceName != "order" {
+		t.Errorf("expected service name %q, got %q", "order", childData.ServiceName)
+	}
+}
+
+func TestTracerRespectsRemoteSampling(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	tracer := NewTracer("order", exporter)
+
+	parent, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	ctx := ContextWithRemoteSpanContext(context.Background(), parent)
+
+	_, span := tracer.Start(ctx, "unsampled")
+	span.End()
+
+	if span.SpanContext().TraceID != parent.TraceID {
+		t.Error("expected span to continue the remote trace")
+	}
+	if n := len(exporter.Spans()); n != 0 {
+		t.Errorf("expected unsampled span not to be exported, got %d spans", n)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	var received http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	exporter := NewInMemoryExporter()
+	tracer := NewTracer("order", exporter)
+	client := &http.Client{Transport: NewTransport(tracer, nil)}
+
+	ctx, parent := tracer.Start(context.Background(), "parent")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/charge", nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	parent.End()
+
+	if req.Header.Get(TraceparentHeader) != "" {
+		t.Error("expected the caller's request not to be modified")
+	}
+
+	sc, ok := Extract(received)
+	if !ok {
+		t.Fatal("expected traceparent header to be sent")
+	}
+
+	spans := exporter.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	clientSpan := spans[0]
+	if sc.SpanID != clientSpan.SpanContext.SpanID {
+		t.Error("expected propagated span ID to be the client span")
+	}
+	if clientSpan.Parent != parent.SpanContext().SpanID {
+		t.Error("expected client span to be a child of the current span")
+	}
+	if clientSpan.Status != StatusError {
+		t.Errorf("expected error status for 502 response, got %v", clientSpan.Status)
+	}
+	if clientSpan.Attributes["http.response.status_code"] != http.StatusBadGateway {
+		t.Errorf("unexpected status code attribute %v", clientSpan.Attributes["http.response.status_code"])
+	}
+}
+
+func TestLogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(NewLogHandler(slog.NewJSONHandler(&buf, nil)))
+	tracer := NewTracer("order", nil)
+
+	ctx, span := tracer.Start(context.Background(), "op")
+	log.InfoContext(ctx, "with span")
+
+	var logData map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &logData); err != nil {
+		t.Fatalf("failed to parse JSON log output: %v", err)
+	}
+	if logData["trace_id"] != span.SpanContext().TraceID.String() {
+		t.Errorf("expected trace_id %s, got %v", span.SpanContext().TraceID, logData["trace_id"])
+	}
+	if logData["span_id"] != span.SpanContext().SpanID.String() {
+		t.Errorf("expected span_id %s, got %v", span.SpanContext().SpanID, logData["span_id"])
+	}
+
+	buf.Reset()
+	log.With("k", "v").InfoContext(context.Background(), "without span")
+	if bytes.Contains(buf.Bytes(), []byte("trace_id")) {
+		t.Errorf("expected no trace_id without a span: %s", buf.String())
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	requests := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" || r.Header.Get("Authorization") != "token" {
+			t.Errorf("unexpected request %s %v", r.URL.Path, r.Header)
+		}
+		body, _ := io.ReadAll(r.Body)
+		requests <- body
+	}))
+	defer server.Close()
+
+	exporter := NewOTLPExporter(server.URL,
+		WithOTLPHeaders(map[string]string{"Authorization": "token"}),
+		WithOTLPBatch(10, time.Hour),
+	)
+	tracer := NewTracer("billing", exporter)
+
+	ctx, parent := tracer.Start(context.Background(), "parent", WithSpanKind(SpanKindServer))
+	_, child := tracer.Start(ctx, "child", WithAttributes("attempt", 2, "ok", true))
+	child.RecordError(errors.New("declined"))
+	child.End()
+	parent.End()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := exporter.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+
+	var body otlpTraces
+	select {
+	case b := <-requests:
+		if err := json.Unmarshal(b, &body); err != nil {
+			t.Fatalf("failed to parse export body: %v", err)
+		}
+	default:
+		t.Fatal("expected spans to be sent on shutdown")
+	}
+
+	if len(body.ResourceSpans) != 1 {
+		t.Fatalf("expected 1 resource, got %d", len(body.ResourceSpans))
+	}
+	rs := body.ResourceSpans[0]
+	if name := *rs.Resource.Attributes[0].Value.StringValue; name != "billing" {
+		t.Errorf("expected service.name billing, got %q", name)
+	}
+	spans := rs.ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	if spans[0].Name != "child" || spans[0].ParentSpanID != spans[1].SpanID || spans[0].Status.Code != int(StatusError) {
+		t.Errorf("unexpected child span %+v", spans[0])
+	}
+	if spans[1].Kind != int(SpanKindServer) || spans[1].TraceID != parent.SpanContext().TraceID.String() {
+		t.Errorf("unexpected parent span %+v", spans[1])
+	}
+
+	if err := exporter.ExportSpans(context.Background(), nil); !errors.Is(err, ErrExporterShutdown) {
+		t.Errorf("expected ErrExporterShutdown after shutdown, got %v", err)
+	}
+}
@@ -1,12 +1,106 @@
 package version
 
-import "runtime/debug"
+import (
+	"runtime"
+	"runtime/debug"
+	"strconv"
+)
 
 var readBuildInfo = debug.ReadBuildInfo
 
-func Version() string {
-	if version, ok := readBuildInfo(); ok {
-		return version.Main.Version
+// Build metadata set at link time, which takes precedence over the build
+// info recorded by the Go toolchain, e.g. for builds without the .git
+// directory:
+//
+//	go build -ldflags "-X github.com/z0mbix/go-microservices-monorepo/pkg/version.version=1.4.2
+//	  -X github.com/z0mbix/go-microservices-monorepo/pkg/version.revision=$(git rev-parse HEAD)"
+var (
+	version    string
+	revision   string
+	commitTime string
+	modified   string
+	buildTime  string
+)
+
+// Info describes the build of the running binary
+type Info struct {
+	// Version is the module version, (devel) for builds from a checkout
+	Version  string `json:"version"`
+	Revision string `json:"revision,omitempty"`
+	// CommitTime is the time of the revision, in RFC 3339 format
+	CommitTime string `json:"commit_time,omitempty"`
+	// Modified reports whether the working tree had uncommitted changes
+	Modified bool `json:"modified"`
+	// BuildTime is only known when set at link time
+	BuildTime string `json:"build_time,omitempty"`
+	GoVersion string `json:"go_version"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+}
+
+// Get returns the build metadata of the running binary
+func Get() Info {
+	info := Info{
+		Version:   "unknown",
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+	}
+	if bi, ok := readBuildInfo(); ok {
+		info.Version = bi.Main.Version
+		if bi.GoVersion != "" {
+			info.GoVersion = bi.GoVersion
+		}
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				info.Revision = s.Value
+			case "vcs.time":
+				info.CommitTime = s.Value
+			case "vcs.modified":
+				info.Modified = s.Value == "true"
+			case "GOOS":
+				info.OS = s.Value
+			case "GOARCH":
+				info.Arch = s.Value
+			}
+		}
+	}
+
+	if version != "" {
+		info.Version = version
+	}
+	if revision != "" {
+		info.Revision = revision
 	}
-	return "unknown"
+	if commitTime != "" {
+		info.CommitTime = commitTime
+	}
+	if m, err := strconv.ParseBool(modified); err == nil {
+		info.Modified = m
+	}
+	info.BuildTime = buildTime
+	return info
+}
+
+// String describes the build on one line, e.g.
+// "1.4.2 (3f2c1ab, modified) go1.24.1 linux/amd64"
+func (i Info) String() string {
+	s := i.Version
+	if i.Revision != "" {
+		rev := i.Revision
+		if len(rev) > 7 {
+			rev = rev[:7]
+		}
+		if i.Modified {
+			rev += ", modified"
+		}
+		s += " (" + rev + ")"
+	}
+	return s + " " + i.GoVersion + " " + i.OS + "/" + i.Arch
+}
+
+// Version returns the version of the running binary
+func Version() string {
+	return Get().Version
 }
@@ -1,6 +1,7 @@
 package version
 
 import (
+	"runtime"
 	"runtime/debug"
 	"testing"
 )
@@ -29,3 +30,62 @@ func TestVersion(t *testing.T) {
 		t.Errorf("Expected 'unknown', got %q", v)
 	}
 }
+
+func TestGet(t *testing.T) {
+	originalReadBuildInfo := readBuildInfo
+	defer func() { readBuildInfo = originalReadBuildInfo }()
+
+	readBuildInfo = func() (*debug.BuildInfo, bool) {
+		return &debug.BuildInfo{
+			GoVersion: "go1.24.1",
+			Main:      debug.Module{Version: "(devel)"},
+			Settings: []debug.BuildSetting{
+				{Key: "GOOS", Value: "linux"},
+				{Key: "GOARCH", Value: "arm64"},
+				{Key: "vcs.revision", Value: "3f2c1ab9d0e4c5b6a7f8e9d0c1b2a3f4e5d6c7b8"},
+				{Key: "vcs.time", Value: "2026-10-01T12:00:00Z"},
+				{Key: "vcs.modified", Value: "true"},
+			},
+		}, true
+	}
+
+	info := Get()
+	expected := Info{
+		Version:    "(devel)",
+		Revision:   "3f2c1ab9d0e4c5b6a7f8e9d0c1b2a3f4e5d6c7b8",
+		CommitTime: "2026-10-01T12:00:00Z",
+		Modified:   true,
+		GoVersion:  "go1.24.1",
+		OS:         "linux",
+		Arch:       "arm64",
+	}
+	if info != expected {
+		t.Errorf("expected %+v, got %+v", expected, info)
+	}
+	if s := info.String(); s != "(devel) (3f2c1ab, modified) go1.24.1 linux/arm64" {
+		t.Errorf("unexpected string %q", s)
+	}
+
+	t.Run("link time values", func(t *testing.T) {
+		defer func() { version, revision, modified, buildTime = "", "", "", "" }()
+		version, revision, modified, buildTime = "1.4.2", "abc1234", "false", "2026-10-02T08:00:00Z"
+
+		info := Get()
+		if info.Version != "1.4.2" || info.Revision != "abc1234" || info.Modified || info.BuildTime != "2026-10-02T08:00:00Z" {
+			t.Errorf("expected link time values to take precedence, got %+v", info)
+		}
+		if info.CommitTime != "2026-10-01T12:00:00Z" {
+			t.Errorf("expected the commit time from the build info, got %q", info.CommitTime)
+		}
+	})
+
+	t.Run("without build info", func(t *testing.T) {
+		readBuildInfo = func() (*debug.BuildInfo, bool) {
+			return nil, false
+		}
+		info := Get()
+		if info.Version != "unknown" || info.GoVersion != runtime.Version() || info.OS != runtime.GOOS || info.Arch != runtime.GOARCH {
+			t.Errorf("expected the runtime's values, got %+v", info)
+		}
+	})
+}
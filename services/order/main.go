@@ -1,9 +1,14 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+
 	"github.com/z0mbix/go-microservices-monorepo/pkg/config"
+	"github.com/z0mbix/go-microservices-monorepo/pkg/discovery"
 	"github.com/z0mbix/go-microservices-monorepo/pkg/service"
-	"github.com/z0mbix/go-microservices-monorepo/pkg/version"
 )
 
 const (
@@ -12,27 +17,34 @@ const (
 )
 
 func main() {
-	serviceVersion := version.Version()
-
-	cfg, err := config.New(
+	cfg, err := config.NewWithArgs(os.Args[1:],
 		config.WithDefaultPort(servicePort),
 	)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	// Order calls the other services, which are ejected from the registry
+	// while their /_ready fails
+	upstreams, err := discovery.Load(cfg, []string{"billing", "shipping", "user"})
 	if err != nil {
 		panic(err)
 	}
 
-	svc, err := service.NewWithName(
-		serviceName,
-		service.WithEnvironment(cfg.Environment),
-		service.WithPort(cfg.Port),
-		service.WithLogLevel(cfg.LogLevel),
-		service.WithVersion(serviceVersion),
+	svc, err := service.FromConfig(serviceName, cfg,
+		service.WithReadinessCheck("upstreams", upstreams, service.NonCritical()),
 	)
 	if err != nil {
 		panic(err)
 	}
 
-	err = svc.Run()
+	ctx := context.Background()
+	upstreams.Watch(ctx)
+
+	err = svc.Run(ctx)
 	if err != nil {
 		panic(err)
 	}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -120,20 +119,7 @@ func TestServiceEndpoints(t *testing.T) {
 		t.Fatalf("failed to create service: %v", err)
 	}
 
-	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		switch r.URL.Path {
-		case "/":
-			fmt.Fprintf(w, "%s service", svc.Name)
-		case "/_ready":
-			fmt.Fprintf(w, "%s service is ready", svc.Name)
-		case "/_live":
-			fmt.Fprintf(w, "%s service is alive", svc.Name)
-		case "/_version":
-			fmt.Fprintf(w, "%s", svc.Version)
-		default:
-			http.NotFound(w, r)
-		}
-	}))
+	testServer := httptest.NewServer(svc.Handler())
 	defer testServer.Close()
 
 	tests := []struct {
@@ -152,7 +138,7 @@ func TestServiceEndpoints(t *testing.T) {
 			name:           "ready endpoint returns readiness status",
 			path:           "/_ready",
 			expectedStatus: http.StatusOK,
-			expectedBody:   "shipping service is ready",
+			expectedBody:   "{\"service\":\"shipping\",\"status\":\"ok\"}\n",
 		},
 		{
 			name:           "live endpoint returns liveness status",
@@ -162,7 +148,7 @@ func TestServiceEndpoints(t *testing.T) {
 		},
 		{
 			name:           "version endpoint returns service version",
-			path:           "/_version",
+			path:           "/_version?format=text",
 			expectedStatus: http.StatusOK,
 			expectedBody:   "test-version",
 		},
@@ -170,7 +156,7 @@ func TestServiceEndpoints(t *testing.T) {
 			name:           "unknown endpoint returns 404",
 			path:           "/unknown",
 			expectedStatus: http.StatusNotFound,
-			expectedBody:   "404 page not found\n",
+			expectedBody:   "{\"error\":\"Not Found\"}\n",
 		},
 	}
 
@@ -1,9 +1,13 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+
 	"github.com/z0mbix/go-microservices-monorepo/pkg/config"
 	"github.com/z0mbix/go-microservices-monorepo/pkg/service"
-	"github.com/z0mbix/go-microservices-monorepo/pkg/version"
 )
 
 const (
@@ -12,27 +16,22 @@ const (
 )
 
 func main() {
-	serviceVersion := version.Version()
-
-	cfg, err := config.New(
+	cfg, err := config.NewWithArgs(os.Args[1:],
 		config.WithDefaultPort(servicePort),
 	)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
 		panic(err)
 	}
 
-	svc, err := service.NewWithName(
-		serviceName,
-		service.WithEnvironment(cfg.Environment),
-		service.WithPort(cfg.Port),
-		service.WithLogLevel(cfg.LogLevel),
-		service.WithVersion(serviceVersion),
-	)
+	svc, err := service.FromConfig(serviceName, cfg)
 	if err != nil {
 		panic(err)
 	}
 
-	err = svc.Run()
+	err = svc.Run(context.Background())
 	if err != nil {
 		panic(err)
 	}